@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+// checkInstructions
+// Dry-runs an instruction string from the start pose against the grid left by the mission's rovers and prints the
+// report. Returns an error if the rover would be lost or stopped, so scripts can refuse to send the command.
+func checkInstructions(options *gridOptions, reader io.Reader, name string, from string, program string, format string, writer io.Writer) error {
+	grid, err := runToGrid(options, reader, name)
+	if err != nil {
+		return err
+	}
+
+	rover, err := input.ParseRover(from, grid)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	instructions, err := input.ParseInstructions(program)
+	if err != nil {
+		return fmt.Errorf("--instructions: %w", err)
+	}
+
+	report := engine.Check(grid, rover, instructions)
+	if format == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report.Record()); err != nil {
+			return err
+		}
+	} else {
+		writeCheckReport(writer, report)
+	}
+
+	switch {
+	case report.Lost:
+		return fmt.Errorf("rover would be lost at instruction %d", report.LostAt)
+	case report.Err != nil:
+		return fmt.Errorf("rover would be stopped: %w", report.Err)
+	}
+	return nil
+}
+
+func writeCheckReport(writer io.Writer, report *engine.CheckReport) {
+	for _, ignored := range report.Ignored {
+		fmt.Fprintf(writer, "instruction %d (%s) ignored: exit heading %s from %s is scented\n",
+			ignored.Index, mars.InstructionCode(ignored.Instruction), ignored.Direction, ignored.Position.String())
+	}
+
+	final := fmt.Sprintf("%d %d %s", report.Position.X, report.Position.Y, report.Direction)
+	switch {
+	case report.Lost:
+		fmt.Fprintf(writer, "LOST at instruction %d (%s): %s LOST\n",
+			report.LostAt, mars.InstructionCode(report.Instructions[report.LostAt]), final)
+	case report.Err != nil:
+		fmt.Fprintf(writer, "STOPPED: %s (%v)\n", final, report.Err)
+	default:
+		fmt.Fprintf(writer, "SAFE: %s\n", final)
+	}
+}
+
+func checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "check",
+		Usage:     "Dry-run an instruction string and report whether it would lose the rover, without changing any scents",
+		ArgsUsage: "[mission-file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "from",
+				Usage:    "Start pose as 'x y D'",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "instructions",
+				Usage:    "Instruction string to check, e.g. 'FRRFLLFFRRFLL'",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format: text or json",
+				Value: "text",
+				Validator: func(format string) error {
+					if format != "text" && format != "json" {
+						return fmt.Errorf("must be text or json")
+					}
+					return nil
+				},
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			options, err := gridOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			reader, name, err := openMission(c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			return checkInstructions(options, reader, name, c.String("from"), c.String("instructions"), c.String("format"), os.Stdout)
+		},
+	}
+}
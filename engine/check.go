@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"errors"
+	"marster-bot/mars"
+)
+
+// IgnoredMove
+// A move a dry run found would be silently ignored because its exit from the grid is scented.
+type IgnoredMove struct {
+	Index       int
+	Instruction mars.Instruction
+	Position    mars.Position
+	Direction   mars.Direction
+}
+
+// CheckReport
+// The outcome of a dry run: the pose the rover would end in, whether and at which instruction it would be lost, the
+// moves scents would ignore, and the error that would stop it early, if any. LostAt is the 0-based index of the
+// instruction that loses the rover and is only meaningful when Lost is set.
+type CheckReport struct {
+	StartPosition  mars.Position
+	StartDirection mars.Direction
+	Instructions   []mars.Instruction
+	Position       mars.Position
+	Direction      mars.Direction
+	Lost           bool
+	LostAt         int
+	Ignored        []IgnoredMove
+	Err            error
+}
+
+// Check
+// Dry-runs the instructions for a rover against a copy of the grid, so neither the grid's scents nor its occupants
+// or listeners are changed, and the rover itself stays where it is.
+func Check(grid *mars.Grid, rover *mars.Rover, instructions []mars.Instruction) *CheckReport {
+	clone := grid.Clone()
+	clone.Vacate(rover)
+	recorder := &mars.Recorder{}
+	clone.Subscribe(recorder)
+
+	probe := mars.NewRover(rover.Position.X, rover.Position.Y, rover.Direction, clone)
+	probe.ID = rover.ID
+
+	report := &CheckReport{
+		StartPosition:  rover.Position.Copy(),
+		StartDirection: rover.Direction,
+		Instructions:   instructions,
+	}
+
+	for i, instruction := range instructions {
+		seen := len(recorder.Events)
+		err := probe.Instruct(instruction)
+
+		for _, event := range recorder.Events[seen:] {
+			if blocked, ok := event.(mars.BlockedByScentEvent); ok {
+				report.Ignored = append(report.Ignored, IgnoredMove{
+					Index:       i,
+					Instruction: instruction,
+					Position:    blocked.Position,
+					Direction:   blocked.Direction,
+				})
+			}
+		}
+
+		if err != nil {
+			var lostErr *mars.LostError
+			if errors.As(err, &lostErr) {
+				report.Lost = true
+				report.LostAt = i
+			}
+			report.Err = err
+			break
+		}
+	}
+
+	report.Position = probe.Position.Copy()
+	report.Direction = probe.Direction
+	return report
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"marster-bot/input"
+	"marster-bot/mars"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		start        *mars.Rover
+		instructions string
+		expected     string
+		lostAt       int
+		ignored      []int
+	}{
+		{
+			name:         "Safe route",
+			start:        mars.NewRover(1, 1, mars.East, nil),
+			instructions: "RFRFRFRF",
+			expected:     "1 1 E",
+			lostAt:       -1,
+		},
+		{
+			name:         "Lost at the instruction that leaves the grid",
+			start:        mars.NewRover(0, 3, mars.West, nil),
+			instructions: "RFRF",
+			expected:     "0 3 N LOST",
+			lostAt:       1,
+		},
+		{
+			name:         "Moves off a scented exit are ignored",
+			start:        mars.NewRover(3, 3, mars.North, nil),
+			instructions: "FFRF",
+			expected:     "4 3 E",
+			lostAt:       -1,
+			ignored:      []int{0, 1},
+		},
+		{
+			name:         "Reports ignored moves before the loss",
+			start:        mars.NewRover(3, 3, mars.North, nil),
+			instructions: "FRFFF",
+			expected:     "5 3 E LOST",
+			lostAt:       4,
+			ignored:      []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := mars.NewGrid(5, 3)
+			grid.AddScent(mars.NewPosition(3, 3), mars.North)
+			recorder := &mars.Recorder{}
+			grid.Subscribe(recorder)
+			tt.start.Grid = grid
+
+			instructions, err := input.ParseInstructions(tt.instructions)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			report := Check(grid, tt.start, instructions)
+
+			result := &RoverResult{Position: report.Position, Direction: report.Direction, Lost: report.Lost}
+			if result.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+			if tt.lostAt >= 0 && (!report.Lost || report.LostAt != tt.lostAt) {
+				t.Errorf("Expected the rover to be lost at instruction %d, got lost=%v at %d", tt.lostAt, report.Lost, report.LostAt)
+			}
+			if len(report.Ignored) != len(tt.ignored) {
+				t.Fatalf("Expected %d ignored moves, got %+v", len(tt.ignored), report.Ignored)
+			}
+			for i, index := range tt.ignored {
+				if report.Ignored[i].Index != index || report.Ignored[i].Position != mars.NewPosition(3, 3) {
+					t.Errorf("Expected instruction %d to be ignored at (3,3), got %+v", index, report.Ignored[i])
+				}
+			}
+
+			if len(grid.ExitScents()) != 1 {
+				t.Errorf("Expected the grid's scents to be unchanged, got %v", grid.ExitScents())
+			}
+			if len(recorder.Events) != 0 {
+				t.Errorf("Expected no events on the grid, got %v", recorder.Events)
+			}
+			if tt.start.Position != report.StartPosition || tt.start.Lost {
+				t.Errorf("Expected the rover itself not to move, got %s", tt.start.Position.String())
+			}
+		})
+	}
+}
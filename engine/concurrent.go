@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"marster-bot/mars"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// Precedence
+// Decides which rover is credited with a scent when several rovers running concurrently fall off the same exit.
+// The zero value credits the rover added first; a seeded precedence shuffles the order, reproducibly for a given
+// seed.
+type Precedence struct {
+	Seeded bool
+	Seed   uint64
+}
+
+// order
+// Returns the indexes of count rovers, highest precedence first.
+func (p Precedence) order(count int) []int {
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	if p.Seeded {
+		random := rand.New(rand.NewPCG(p.Seed, 0))
+		random.Shuffle(count, func(i, j int) {
+			order[i], order[j] = order[j], order[i]
+		})
+	}
+	return order
+}
+
+// concurrentRun
+// A queued rover running against its own copy of the grid, and the events it published there.
+type concurrentRun struct {
+	pending  pendingRover
+	grid     *mars.Grid
+	recorder *mars.Recorder
+	result   *RoverResult
+}
+
+// RunConcurrent
+// Executes every queued rover at once on up to workers goroutines (all available CPUs when workers is not
+// positive) and returns their results in the order they were added.
+//
+// Rovers in a batch are independent: each runs against a copy of the grid as it was when the batch started, so it
+// sees earlier scents, obstacles and parked rovers but not the other rovers in the batch. Once every rover has
+// finished, events are replayed to the grid's listeners and rovers are parked in the order they were added, and new
+// scents are merged into the grid in precedence order, so the outcome never depends on how the goroutines were
+// scheduled. Rovers in a batch can only meet on the cells they finish on: a rover that finishes on a cell an earlier
+// rover already holds is handled by the grid's collision policy, see park.
+func (s *Simulation) RunConcurrent(workers int, precedence Precedence) []*RoverResult {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	snapshot := s.Grid.Clone()
+	runs := make([]*concurrentRun, len(s.pending))
+	for i, p := range s.pending {
+		run := &concurrentRun{pending: p, grid: snapshot.Clone(), recorder: &mars.Recorder{}}
+		run.grid.Subscribe(run.recorder)
+		runs[i] = run
+	}
+	s.pending = nil
+
+	queue := make(chan *concurrentRun)
+	var wg sync.WaitGroup
+	for range min(workers, len(runs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range queue {
+				run.pending.rover.Grid = run.grid
+				run.result = s.runRover(run.grid, run.pending)
+			}
+		}()
+	}
+	for _, run := range runs {
+		queue <- run
+	}
+	close(queue)
+	wg.Wait()
+
+	for _, run := range runs {
+		for _, event := range run.recorder.Events {
+			if _, ok := event.(mars.ScentAddedEvent); !ok {
+				s.Grid.Publish(event)
+			}
+		}
+	}
+
+	for _, run := range runs {
+		s.park(run)
+	}
+	for _, i := range precedence.order(len(runs)) {
+		s.merge(snapshot, runs[i])
+	}
+
+	results := make([]*RoverResult, 0, len(runs))
+	for _, run := range runs {
+		results = append(results, run.result)
+	}
+	s.results = append(s.results, results...)
+	return results
+}
+
+// park
+// Moves a finished rover back onto the simulation's grid. If another rover already holds the cell it finished on, the
+// grid's collision policy decides: CollisionAllow shares the cell and records the collision, while CollisionBlock and
+// CollisionAbort move the rover back along its trail to the nearest free cell, as if it had stopped in front of the
+// other rover, and CollisionAbort also halts it with a CollisionError.
+func (s *Simulation) park(run *concurrentRun) {
+	rover := run.pending.rover
+	rover.Grid = s.Grid
+	if !run.grid.Vacate(rover) {
+		return
+	}
+
+	other := s.Grid.OccupantAt(rover.Position, rover)
+	if other == nil {
+		s.Grid.Occupy(rover)
+		return
+	}
+
+	collision := mars.Collision{Position: rover.Position.Copy(), Other: other.ID}
+	s.Grid.Publish(mars.CollidedEvent{Rover: rover, Other: other, Position: collision.Position})
+	result := run.result
+	switch s.Grid.CollisionPolicy {
+	case mars.CollisionAbort:
+		result.Collision = &mars.CollisionError{Position: collision.Position, Rover: rover.ID, Other: other.ID}
+		result.Err = result.Collision
+	default:
+		rover.Collisions = append(rover.Collisions, collision)
+	}
+
+	if s.Grid.CollisionPolicy != mars.CollisionAllow {
+		for i := len(rover.Trail) - 2; i >= 0; i-- {
+			if s.Grid.OccupantAt(rover.Trail[i], rover) == nil {
+				rover.Position = rover.Trail[i]
+				rover.Trail = rover.Trail[:i+1]
+				break
+			}
+		}
+	}
+
+	s.Grid.Occupy(rover)
+	result.Position = rover.Position.Copy()
+	result.Collisions = rover.Collisions
+	result.Trail = rover.Trail
+}
+
+// merge
+// Adds the scents a finished rover left to the simulation's grid. A rover is only credited with a scent if no rover
+// ahead of it in precedence order left the same one.
+func (s *Simulation) merge(snapshot *mars.Grid, run *concurrentRun) {
+	run.result.ScentAdded = nil
+	for _, scent := range run.grid.ExitScents() {
+		if snapshot.IsExitScented(scent.Position, scent.Direction) {
+			continue
+		}
+		if s.Grid.LeaveScent(scent) {
+			position := scent.Position
+			run.result.ScentAdded = &position
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"marster-bot/input"
+	"marster-bot/mars"
+	"testing"
+)
+
+// queueEdgeRovers
+// Queues rovers that all fall off the north edge at (3,3), after one that survives.
+func queueEdgeRovers(sim *Simulation, count int) {
+	sim.AddRover(mars.NewRover(1, 1, mars.East, sim.Grid), []mars.Instruction{right(), forward(), right(), forward()})
+	for range count {
+		sim.AddRover(mars.NewRover(3, 2, mars.North, sim.Grid), []mars.Instruction{forward(), forward()})
+	}
+}
+
+func scentCredits(results []*RoverResult) []int {
+	var credited []int
+	for _, result := range results {
+		if result.ScentAdded != nil {
+			credited = append(credited, result.Number)
+		}
+	}
+	return credited
+}
+
+func TestSimulationRunConcurrent(t *testing.T) {
+	t.Run("Rovers in a batch do not see each other's scents", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+		queueEdgeRovers(sim, 3)
+
+		results := sim.RunConcurrent(4, Precedence{})
+		if len(results) != 4 {
+			t.Fatalf("Expected 4 results, got %d", len(results))
+		}
+		for i, result := range results {
+			if result.Number != i+1 {
+				t.Errorf("Expected results in the order rovers were added, got #%d at %d", result.Number, i)
+			}
+		}
+		for _, result := range results[1:] {
+			if !result.Lost {
+				t.Errorf("Expected rover #%d to be lost without another rover's scent, got %s", result.Number, result)
+			}
+		}
+		if credited := scentCredits(results); len(credited) != 1 || credited[0] != 2 {
+			t.Errorf("Expected only rover #2 to be credited with the scent, got %v", credited)
+		}
+		if len(sim.Grid.ExitScents()) != 1 {
+			t.Errorf("Expected a single merged scent, got %v", sim.Grid.ExitScents())
+		}
+	})
+
+	t.Run("A rover saved by an earlier rover's scent in order is lost in a batch", func(t *testing.T) {
+		// The classic mission: run in order, rover #3 is saved by the scent rover #2 leaves at (3,3)
+		classic := func() *Simulation {
+			sim := newTestSimulation(5, 3)
+			for _, rover := range []struct{ pose, program string }{
+				{"1 1 E", "RFRFRFRF"},
+				{"3 2 N", "FRRFLLFFRRFLL"},
+				{"0 3 W", "LLFFFLFLFL"},
+			} {
+				r, err := input.ParseRover(rover.pose, sim.Grid)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				instructions, err := input.ParseInstructions(rover.program)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				sim.AddRover(r, instructions)
+			}
+			return sim
+		}
+
+		sequential := classic().Run()
+		concurrent := classic().RunConcurrent(4, Precedence{})
+		if got := sequential[2].String(); got != "2 3 S" {
+			t.Errorf("Expected rover #3 to be saved by the scent in order, got %s", got)
+		}
+		if got := concurrent[2].String(); got != "3 3 N LOST" {
+			t.Errorf("Expected rover #3 to be lost without rover #2's scent, got %s", got)
+		}
+		if got := concurrent[1].String(); got != sequential[1].String() {
+			t.Errorf("Expected rover #2 to end the same either way, got %s and %s", sequential[1], got)
+		}
+	})
+
+	t.Run("Rovers see scents and rovers from before the batch", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+		queueEdgeRovers(sim, 1)
+		sim.Run()
+
+		sim.AddRover(mars.NewRover(3, 2, mars.North, sim.Grid), []mars.Instruction{forward(), forward()})
+		sim.AddRover(mars.NewRover(1, 0, mars.West, sim.Grid), []mars.Instruction{forward()})
+		results := sim.RunConcurrent(2, Precedence{})
+
+		if results[0].Lost || results[0].String() != "3 3 N" {
+			t.Errorf("Expected the earlier scent to protect rover #3, got %s", results[0])
+		}
+		if len(results[1].Collisions) != 1 || results[1].Collisions[0].Other != 1 {
+			t.Errorf("Expected rover #4 to run into parked rover #1, got %v", results[1].Collisions)
+		}
+		if other := sim.Grid.OccupantAt(mars.NewPosition(3, 3), nil); other == nil || other.ID != 3 {
+			t.Errorf("Expected rover #3 to be parked on the shared grid, got %v", other)
+		}
+	})
+
+	t.Run("Rovers that finish on the same cell collide", func(t *testing.T) {
+		run := func(policy mars.CollisionPolicy) []*RoverResult {
+			sim := newTestSimulation(5, 5)
+			sim.Grid.CollisionPolicy = policy
+			sim.AddRover(mars.NewRover(1, 1, mars.North, sim.Grid), []mars.Instruction{forward()})
+			sim.AddRover(mars.NewRover(1, 3, mars.South, sim.Grid), []mars.Instruction{forward()})
+			return sim.RunConcurrent(2, Precedence{})
+		}
+
+		blocked := run(mars.CollisionBlock)
+		if blocked[0].String() != "1 2 N" || blocked[1].String() != "1 3 S" {
+			t.Errorf("Expected the same poses as a sequential run, got %s and %s", blocked[0], blocked[1])
+		}
+		if len(blocked[1].Collisions) != 1 || blocked[1].Collisions[0].Other != 1 {
+			t.Errorf("Expected rover #2 to record running into rover #1, got %v", blocked[1].Collisions)
+		}
+
+		aborted := run(mars.CollisionAbort)
+		if aborted[1].Collision == nil || aborted[1].String() != "1 3 S COLLIDED #1 (1,2)" {
+			t.Errorf("Expected rover #2 to be halted by the collision, got %s", aborted[1])
+		}
+
+		allowed := run(mars.CollisionAllow)
+		if allowed[1].String() != "1 2 S" || len(allowed[1].Collisions) != 1 {
+			t.Errorf("Expected rover #2 to share the cell with a recorded collision, got %s %v",
+				allowed[1], allowed[1].Collisions)
+		}
+	})
+
+	t.Run("Seeded precedence is reproducible", func(t *testing.T) {
+		run := func(precedence Precedence) []int {
+			sim := newTestSimulation(5, 3)
+			queueEdgeRovers(sim, 8)
+			return scentCredits(sim.RunConcurrent(8, precedence))
+		}
+
+		first := run(Precedence{Seeded: true, Seed: 42})
+		if len(first) != 1 {
+			t.Fatalf("Expected one rover to be credited, got %v", first)
+		}
+		for range 20 {
+			if again := run(Precedence{Seeded: true, Seed: 42}); len(again) != 1 || again[0] != first[0] {
+				t.Fatalf("Expected rover #%d to be credited every run, got %v", first[0], again)
+			}
+		}
+	})
+
+	t.Run("Events are replayed in the order rovers were added", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+		recorder := &mars.Recorder{}
+		sim.Grid.Subscribe(recorder)
+		queueEdgeRovers(sim, 3)
+		sim.RunConcurrent(4, Precedence{})
+
+		last := 0
+		for _, event := range recorder.Events {
+			if instructed, ok := event.(mars.InstructedEvent); ok {
+				if instructed.Rover.ID < last {
+					t.Fatalf("Expected rover #%d's events before rover #%d's", last, instructed.Rover.ID)
+				}
+				last = instructed.Rover.ID
+			}
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"marster-bot/mars"
+	"marster-bot/output"
+)
+
+type consoleListener struct {
+	console *output.Console
+}
+
+// NewConsoleListener
+// Returns a listener that narrates grid events as console debug lines.
+func NewConsoleListener(console *output.Console) mars.Listener {
+	return &consoleListener{console: console}
+}
+
+func (l *consoleListener) OnEvent(event mars.Event) {
+	switch e := event.(type) {
+	case mars.InstructedEvent:
+		l.console.Debug("Current position: %d %d %s", e.Rover.Position.X, e.Rover.Position.Y, e.Rover.Direction)
+		l.console.Debug("Processing instruction: %v", e.Instruction)
+	case mars.MovedEvent:
+		l.console.Debug("Rover moved to (%d, %d)", e.To.X, e.To.Y)
+	case mars.RotatedEvent:
+		l.console.Debug("Rover turned from %s to %s", e.From, e.To)
+	case mars.BlockedByScentEvent:
+		l.console.Debug("Rover ignored move off the grid at (%d, %d) because of a scent", e.Position.X, e.Position.Y)
+	case mars.BlockedByObstacleEvent:
+		l.console.Debug("Rover blocked by obstacle at (%d, %d)", e.Obstacle.X, e.Obstacle.Y)
+	case mars.CollidedEvent:
+		l.console.Debug("Rover #%d ran into rover #%d at (%d, %d)", e.Rover.ID, e.Other.ID, e.Position.X, e.Position.Y)
+	case mars.ReachedEdgeEvent:
+		l.console.Debug("Rover reached the edge at (%d, %d) heading %s: %s", e.Position.X, e.Position.Y, e.Direction, e.Policy)
+	case mars.FellOffEvent:
+		l.console.Debug("Rover fell off the grid at (%d, %d) facing %s", e.Position.X, e.Position.Y, e.Direction)
+	case mars.ScentAddedEvent:
+		l.console.Debug("Scent left at (%d, %d)", e.Position.X, e.Position.Y)
+	}
+}
@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"marster-bot/mars"
+	"strings"
+)
+
+var (
+	// ErrNothingToUndo is returned by History.Undo when every step has already been undone.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by History.Redo when no undone step is left to redo.
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// state
+// A snapshot of the simulation between steps: the grid's scents and occupants, every result so far, and the number
+// of rovers added.
+type state struct {
+	grid    *mars.Grid
+	results []*RoverResult
+	count   int
+}
+
+// step
+// One undoable step: placing a rover, or one of its instructions. Applied is the number of the rover's
+// instructions that have run once the step is applied.
+type step struct {
+	entry   int
+	applied int
+	state   state
+}
+
+// HistoryEntry
+// A rover in the history, with how many of its instructions are currently applied and its result with just those
+// instructions. Applied is -1 and Result nil when the rover itself has been undone. Instructions after one that
+// lost or stopped the rover never ran, so they cannot be undone or redone.
+type HistoryEntry struct {
+	Number         int
+	StartPosition  mars.Position
+	StartDirection mars.Direction
+	Instructions   []mars.Instruction
+	Applied        int
+	Result         *RoverResult
+	ran            int
+}
+
+// String
+// Formats the entry as a history line, marking how far through its instructions the session is with '|',
+// e.g. '#2 3 2 N FRR|FLL -> 3 3 E'.
+func (e HistoryEntry) String() string {
+	line := fmt.Sprintf("#%d %d %d %s ", e.Number, e.StartPosition.X, e.StartPosition.Y, e.StartDirection)
+	if e.Applied < 0 {
+		return line + Program(e.Instructions) + " (undone)"
+	}
+
+	line += Program(e.Instructions[:e.Applied])
+	if e.Applied < e.ran {
+		line += "|"
+	}
+	line += Program(e.Instructions[e.Applied:])
+	return fmt.Sprintf("%s -> %s", strings.TrimSpace(line), e.Result)
+}
+
+// History
+// Runs rovers on a simulation while keeping a snapshot of the grid and results after every step, so the session
+// can be rolled back one instruction or one rover at a time, and rolled forward again. Running a new rover drops
+// any steps that were undone.
+type History struct {
+	Simulation *Simulation
+	initial    state
+	entries    []HistoryEntry
+	steps      []step
+	cursor     int
+}
+
+func NewHistory(simulation *Simulation) *History {
+	h := &History{Simulation: simulation}
+	h.initial = h.capture()
+	return h
+}
+
+// Run
+// Runs a rover's instructions on the simulation and records a step for placing it and for each instruction.
+func (h *History) Run(rover *mars.Rover, instructions []mars.Instruction) []*RoverResult {
+	h.steps = h.steps[:h.cursor]
+	if h.cursor > 0 {
+		last := h.steps[h.cursor-1]
+		h.entries = h.entries[:last.entry+1]
+		h.entries[last.entry].Instructions = h.entries[last.entry].Instructions[:last.applied]
+		h.entries[last.entry].ran = last.applied
+	} else {
+		h.entries = nil
+	}
+
+	before := h.capture()
+	start, direction := rover.Position.Copy(), rover.Direction
+
+	number := h.Simulation.AddRover(rover, instructions)
+	results := h.Simulation.Run()
+
+	entry := len(h.entries)
+	h.entries = append(h.entries, HistoryEntry{
+		Number:         number,
+		StartPosition:  start,
+		StartDirection: direction,
+		Instructions:   instructions,
+	})
+	for applied := 0; applied <= len(instructions); applied++ {
+		after := replay(before, mars.NewRover(start.X, start.Y, direction, nil), instructions[:applied])
+		h.steps = append(h.steps, step{entry: entry, applied: applied, state: after})
+		h.entries[entry].ran = applied
+
+		if after.results[len(after.results)-1].Err != nil {
+			break
+		}
+	}
+	// The replayed result only lists the instructions that ran, so the last step keeps the rover's own result,
+	// with the whole program it was given, for redo to bring back unchanged
+	if len(results) == 1 {
+		last := h.steps[len(h.steps)-1].state.results
+		last[len(last)-1] = results[0]
+	}
+	h.cursor = len(h.steps)
+
+	return results
+}
+
+// replay
+// Runs a copy of a rover from a snapshot on a copy of its grid, with no listeners, and returns the state it leaves.
+func replay(before state, rover *mars.Rover, instructions []mars.Instruction) state {
+	simulation := &Simulation{Grid: before.grid.Clone(), count: before.count}
+	rover.Grid = simulation.Grid
+	simulation.AddRover(rover, instructions)
+
+	results := append(append([]*RoverResult(nil), before.results...), simulation.Run()...)
+	return state{grid: simulation.Grid, results: results, count: simulation.count}
+}
+
+// Undo
+// Rolls back the last instruction, or the placing of the last rover once all its instructions are undone.
+func (h *History) Undo() error {
+	if h.cursor == 0 {
+		return ErrNothingToUndo
+	}
+	h.cursor--
+	h.restore()
+	return nil
+}
+
+// UndoRover
+// Rolls back the last rover and every instruction it ran.
+func (h *History) UndoRover() error {
+	if h.cursor == 0 {
+		return ErrNothingToUndo
+	}
+	entry := h.steps[h.cursor-1].entry
+	for h.cursor > 0 && h.steps[h.cursor-1].entry == entry {
+		h.cursor--
+	}
+	h.restore()
+	return nil
+}
+
+// Redo
+// Re-applies the last undone step.
+func (h *History) Redo() error {
+	if h.cursor == len(h.steps) {
+		return ErrNothingToRedo
+	}
+	h.cursor++
+	h.restore()
+	return nil
+}
+
+// RedoRover
+// Re-applies every undone step of the next rover.
+func (h *History) RedoRover() error {
+	if h.cursor == len(h.steps) {
+		return ErrNothingToRedo
+	}
+	entry := h.steps[h.cursor].entry
+	for h.cursor < len(h.steps) && h.steps[h.cursor].entry == entry {
+		h.cursor++
+	}
+	h.restore()
+	return nil
+}
+
+// Entries
+// Returns every rover in the history, including undone ones that can still be redone, oldest first.
+func (h *History) Entries() []HistoryEntry {
+	entries := make([]HistoryEntry, len(h.entries))
+	for i, entry := range h.entries {
+		entry.Applied = -1
+		entries[i] = entry
+	}
+
+	for _, s := range h.steps[:h.cursor] {
+		entries[s.entry].Applied = s.applied
+		entries[s.entry].Result = s.state.results[len(s.state.results)-1]
+	}
+	return entries
+}
+
+func (h *History) capture() state {
+	return state{
+		grid:    h.Simulation.Grid.Clone(),
+		results: append([]*RoverResult(nil), h.Simulation.results...),
+		count:   h.Simulation.count,
+	}
+}
+
+// restore
+// Rolls the simulation to the state after the last applied step.
+func (h *History) restore() {
+	current := h.initial
+	if h.cursor > 0 {
+		current = h.steps[h.cursor-1].state
+	}
+
+	h.Simulation.Grid.Restore(current.grid)
+	h.Simulation.results = append([]*RoverResult(nil), current.results...)
+	h.Simulation.count = current.count
+}
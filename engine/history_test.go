@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"errors"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	run := func(t *testing.T, history *History, pose, program string) {
+		t.Helper()
+		rover, err := input.ParseRover(pose, history.Simulation.Grid)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		instructions, err := input.ParseInstructions(program)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		history.Run(rover, instructions)
+	}
+
+	lines := func(history *History) []string {
+		var lines []string
+		for _, entry := range history.Entries() {
+			lines = append(lines, entry.String())
+		}
+		return lines
+	}
+
+	expectLines := func(t *testing.T, history *History, expected ...string) {
+		t.Helper()
+		got := lines(history)
+		if len(got) != len(expected) {
+			t.Fatalf("Expected history %q, got %q", expected, got)
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("Expected history line %d to be %q, got %q", i, expected[i], got[i])
+			}
+		}
+	}
+
+	t.Run("Undoing the losing instruction removes its scent", func(t *testing.T) {
+		history := NewHistory(newTestSimulation(5, 3))
+		run(t, history, "3 2 N", "FRRFLLFFRRFLL")
+		expectLines(t, history, "#1 3 2 N FRRFLLFFRRFLL -> 3 3 N LOST")
+
+		if err := history.Undo(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectLines(t, history, "#1 3 2 N FRRFLLF|FRRFLL -> 3 3 N")
+		if scents := history.Simulation.Grid.ExitScents(); len(scents) != 0 {
+			t.Errorf("Expected the scent to be removed, got %v", scents)
+		}
+		if occupant := history.Simulation.Grid.OccupantAt(mars.NewPosition(3, 3), nil); occupant == nil {
+			t.Error("Expected the rover to be back on the grid at (3,3)")
+		}
+
+		if err := history.Redo(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !history.Simulation.Grid.IsExitScented(mars.NewPosition(3, 3), mars.North) {
+			t.Error("Expected redo to put the scent back")
+		}
+		if !errors.Is(history.Redo(), ErrNothingToRedo) {
+			t.Error("Expected nothing to redo")
+		}
+	})
+
+	t.Run("Redo gives back the original result", func(t *testing.T) {
+		history := NewHistory(newTestSimulation(5, 3))
+		run(t, history, "3 2 N", "FFFRF")
+		original := history.Simulation.Results()[0].Record()
+		if program := Program(history.Simulation.Results()[0].Instructions); program != "FFFRF" {
+			t.Fatalf("Expected the whole program, got %q", program)
+		}
+
+		for _, undoRedo := range []func() error{
+			func() error { history.UndoRover(); return history.RedoRover() },
+			func() error { history.Undo(); return history.Redo() },
+		} {
+			if err := undoRedo(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			results := history.Simulation.Results()
+			if len(results) != 1 || !reflect.DeepEqual(results[0].Record(), original) {
+				t.Errorf("Expected %+v after redo, got %+v", original, results[0].Record())
+			}
+		}
+	})
+
+	t.Run("Undoing a rover clears it and its trail", func(t *testing.T) {
+		history := NewHistory(newTestSimulation(5, 3))
+		run(t, history, "1 1 E", "RFRFRFRF")
+		run(t, history, "3 2 N", "FRRFLLFFRRFLL")
+
+		if err := history.UndoRover(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectLines(t, history, "#1 1 1 E RFRFRFRF -> 1 1 E", "#2 3 2 N FRRFLLFFRRFLL (undone)")
+		if len(history.Simulation.Results()) != 1 || len(history.Simulation.Grid.ExitScents()) != 0 {
+			t.Errorf("Expected only rover #1 to remain, got %v", history.Simulation.Results())
+		}
+
+		run(t, history, "0 3 W", "LLFFFLF")
+		expectLines(t, history, "#1 1 1 E RFRFRFRF -> 1 1 E", "#2 0 3 W LLFFFLF -> 3 3 N LOST")
+		if number := history.Simulation.Results()[1].Number; number != 2 {
+			t.Errorf("Expected the new rover to take number 2, got %d", number)
+		}
+	})
+
+	t.Run("Running a rover after undoing drops the undone steps", func(t *testing.T) {
+		history := NewHistory(newTestSimulation(5, 3))
+		run(t, history, "1 1 E", "FFF")
+		history.Undo()
+		history.Undo()
+		run(t, history, "0 0 N", "F")
+
+		expectLines(t, history, "#1 1 1 E F -> 2 1 E", "#2 0 0 N F -> 0 1 N")
+		history.UndoRover()
+		history.UndoRover()
+		expectLines(t, history, "#1 1 1 E F (undone)", "#2 0 0 N F (undone)")
+		if !errors.Is(history.Undo(), ErrNothingToUndo) {
+			t.Error("Expected nothing to undo")
+		}
+		if len(history.Simulation.Grid.Occupants()) != 0 {
+			t.Errorf("Expected an empty grid, got %v", history.Simulation.Grid.Occupants())
+		}
+
+		history.Redo()
+		expectLines(t, history, "#1 1 1 E |F -> 1 1 E", "#2 0 0 N F (undone)")
+		history.RedoRover()
+		expectLines(t, history, "#1 1 1 E F -> 2 1 E", "#2 0 0 N F (undone)")
+	})
+}
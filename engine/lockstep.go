@@ -0,0 +1,450 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"marster-bot/mars"
+	"strings"
+)
+
+// ErrNotLockstep
+// Returned when a queued rover has an instruction lock-step runs cannot split into ticks, such as one registered
+// from another package.
+var ErrNotLockstep = errors.New("instruction cannot run in lock-step")
+
+// Statuses of a rover at the end of a tick.
+const (
+	StatusActive  = "active"
+	StatusBlocked = "blocked"
+	StatusDone    = "done"
+	StatusLost    = "lost"
+	StatusStopped = "stopped"
+)
+
+// TickPose
+// A rover's pose at the end of a tick, and whether it is still running: active, blocked this tick by another
+// rover, done, lost, or stopped by an obstacle, a collision or an error.
+type TickPose struct {
+	Number    int
+	Position  mars.Position
+	Direction mars.Direction
+	Status    string
+}
+
+// Tick
+// Every rover's pose at the end of a tick. Tick 0 holds the starting poses.
+type Tick struct {
+	Number int
+	Poses  []TickPose
+}
+
+// String
+// Formats the tick as a timeline line, e.g. 'tick 2: #1 1 0 S | #2 3 3 N lost'. Statuses other than active are
+// appended to the pose.
+func (t Tick) String() string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "tick %d:", t.Number)
+	for i, pose := range t.Poses {
+		if i > 0 {
+			line.WriteString(" |")
+		}
+		fmt.Fprintf(&line, " #%d %d %d %s", pose.Number, pose.Position.X, pose.Position.Y, pose.Direction)
+		if pose.Status != StatusActive {
+			line.WriteString(" " + pose.Status)
+		}
+	}
+	return line.String()
+}
+
+// action
+// One tick's worth of an instruction: a rotation, or a single step of a movement. Conditional and loop instructions
+// are actions too until the rover reaches them and decides which instructions they stand for. Passes counts the
+// times a loop has already run its body.
+type action struct {
+	instruction mars.Instruction
+	group       int
+	first       bool
+	passes      int
+}
+
+// lockstepRover
+// A rover taking part in a lock-step run, with its instructions expanded into one action per tick.
+type lockstepRover struct {
+	rover   *mars.Rover
+	result  *RoverResult
+	actions []action
+	next    int
+	groups  int
+	status  string
+}
+
+// expand
+// Splits instructions into single-tick actions, so a multi-space move takes one tick per space.
+func expand(instructions []mars.Instruction) []action {
+	var actions []action
+	for group, instruction := range instructions {
+		count := 1
+		if movement, ok := instruction.(*mars.MovementInstruction); ok {
+			count = max(int(movement.Distance), -int(movement.Distance))
+		}
+		for i := range count {
+			actions = append(actions, action{instruction: instruction, group: group, first: i == 0})
+		}
+	}
+	return actions
+}
+
+// decide
+// Replaces conditional and loop instructions at the head of the rover's actions with the instructions its sensors
+// pick at the start of the tick, so deciding takes no tick of its own.
+func (r *lockstepRover) decide(grid *mars.Grid) *mars.LoopLimitError {
+	for r.next < len(r.actions) {
+		current := r.actions[r.next]
+
+		var chosen []action
+		switch instruction := current.instruction.(type) {
+		case *mars.ConditionalInstruction:
+			grid.Publish(mars.InstructedEvent{Rover: r.rover, Instruction: instruction})
+			chosen = r.expandGroups(instruction.Branch(r.rover))
+		case *mars.LoopInstruction:
+			if current.passes == 0 {
+				grid.Publish(mars.InstructedEvent{Rover: r.rover, Instruction: instruction})
+			}
+			// A loop whose condition holds is dropped; otherwise its body runs and the loop comes round again
+			if !r.rover.Sense(instruction.Until) {
+				if current.passes == mars.MaxLoopIterations {
+					return &mars.LoopLimitError{Until: instruction.Until}
+				}
+				current.passes++
+				chosen = append(r.expandGroups(instruction.Body), current)
+			}
+		default:
+			return nil
+		}
+
+		rest := append(chosen, r.actions[r.next+1:]...)
+		r.actions = append(r.actions[:r.next], rest...)
+	}
+	return nil
+}
+
+// expandGroups
+// Expands instructions chosen by a conditional or loop, numbering their groups after every group used so far.
+func (r *lockstepRover) expandGroups(instructions []mars.Instruction) []action {
+	actions := expand(instructions)
+	for i := range actions {
+		actions[i].group += r.groups
+	}
+	r.groups += len(instructions)
+	return actions
+}
+
+// running
+// Reports whether the rover has actions left and has not been lost or stopped.
+func (r *lockstepRover) running() bool {
+	return (r.status == StatusActive || r.status == StatusBlocked) && r.next < len(r.actions)
+}
+
+// skipRest
+// Drops the remaining steps of the current instruction, as a sequential run does when a move is cut short.
+func (r *lockstepRover) skipRest() {
+	group := r.actions[r.next-1].group
+	for r.next < len(r.actions) && r.actions[r.next].group == group {
+		r.next++
+	}
+}
+
+func (r *lockstepRover) stop(err error) {
+	r.result.Err = err
+	r.status = StatusStopped
+}
+
+// RunLockstep
+// Executes every queued rover in lock-step: on each tick every running rover performs its next action, where a
+// multi-space move takes one tick per space. Rovers that would end a tick in the same cell, swap cells, or move
+// into a rover that is staying put are handled by the grid's CollisionPolicy: blocked in place, aborted, or
+// allowed through with a warning. Under CollisionBlock every rover contesting a cell is blocked, so two rovers
+// heading for the same cell both stay where they are. Scents left on a tick only protect rovers from the next tick.
+// Returns each rover's result and every rover's pose at the end of each tick.
+//
+// Only the built-in rotations, moves, conditionals and loops can be split into ticks. If any queued rover has
+// another kind of instruction, nothing is run, the rovers stay queued, and an error wrapping ErrNotLockstep is
+// returned.
+func (s *Simulation) RunLockstep() ([]*RoverResult, []Tick, error) {
+	for _, p := range s.pending {
+		if err := checkLockstep(p.instructions); err != nil {
+			return nil, nil, fmt.Errorf("rover #%d: %w", p.number, err)
+		}
+	}
+
+	rovers := make([]*lockstepRover, 0, len(s.pending))
+	for _, p := range s.pending {
+		r := &lockstepRover{
+			rover:   p.rover,
+			actions: expand(p.instructions),
+			groups:  len(p.instructions),
+			status:  StatusActive,
+			result: &RoverResult{
+				Number:         p.number,
+				StartPosition:  p.rover.Position.Copy(),
+				StartDirection: p.rover.Direction,
+				Instructions:   p.instructions,
+			},
+		}
+		if err := place(s.Grid, p.rover); err != nil {
+			r.result.Collision = err
+			r.stop(err)
+		} else if len(r.actions) == 0 {
+			r.status = StatusDone
+		}
+		rovers = append(rovers, r)
+	}
+	s.pending = nil
+
+	ticks := []Tick{snapshot(0, rovers)}
+	for number := 1; anyRunning(rovers); number++ {
+		s.tick(rovers)
+		ticks = append(ticks, snapshot(number, rovers))
+	}
+
+	results := make([]*RoverResult, 0, len(rovers))
+	for _, r := range rovers {
+		result := r.result
+		result.Position = r.rover.Position.Copy()
+		result.Direction = r.rover.Direction
+		result.Lost = r.rover.Lost
+		result.Collisions = r.rover.Collisions
+		result.Trail = r.rover.Trail
+		results = append(results, result)
+	}
+	s.results = append(s.results, results...)
+	return results, ticks, nil
+}
+
+// checkLockstep
+// Returns an error wrapping ErrNotLockstep for the first instruction, including those inside conditionals and loops,
+// that lock-step runs cannot split into ticks.
+func checkLockstep(instructions []mars.Instruction) error {
+	for _, instruction := range instructions {
+		var err error
+		switch instruction := instruction.(type) {
+		case *mars.RotationInstruction, *mars.MovementInstruction:
+		case *mars.ConditionalInstruction:
+			if err = checkLockstep(instruction.Then); err == nil {
+				err = checkLockstep(instruction.Else)
+			}
+		case *mars.LoopInstruction:
+			err = checkLockstep(instruction.Body)
+		default:
+			err = fmt.Errorf("%w: %v", ErrNotLockstep, instruction)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockstepMove
+// A rover's intended step on the current tick, and the rover it conflicts with if it is held back.
+type lockstepMove struct {
+	rover   *lockstepRover
+	to      mars.Position
+	blocked bool
+	other   *mars.Rover
+}
+
+// tick
+// Performs one action for every running rover.
+func (s *Simulation) tick(rovers []*lockstepRover) {
+	var moves []*lockstepMove
+	var falls []*lockstepRover
+	headings := map[*lockstepRover]mars.Direction{}
+
+	for _, r := range rovers {
+		if r.status == StatusBlocked {
+			r.status = StatusActive
+		}
+		if r.running() {
+			if err := r.decide(s.Grid); err != nil {
+				r.result.Loop = err
+				r.stop(err)
+			}
+		}
+		if !r.running() {
+			if r.status == StatusActive {
+				r.status = StatusDone
+			}
+			continue
+		}
+		current := r.actions[r.next]
+		r.next++
+		if current.first {
+			s.Grid.Publish(mars.InstructedEvent{Rover: r.rover, Instruction: current.instruction})
+		}
+
+		switch instruction := current.instruction.(type) {
+		case *mars.RotationInstruction:
+			r.rover.Rotate(instruction.Orientation)
+		case *mars.MovementInstruction:
+			heading := r.rover.Direction
+			if instruction.Distance < 0 {
+				heading = heading.Rotate(mars.Around)
+			}
+
+			step := r.rover.PlanStep(heading)
+			r.rover.Report(step)
+			switch step.Kind {
+			case mars.StepMove, mars.StepWrap:
+				moves = append(moves, &lockstepMove{rover: r, to: step.To})
+			case mars.StepFall:
+				falls = append(falls, r)
+				headings[r] = heading
+			case mars.StepScented, mars.StepClamp:
+				r.skipRest()
+			case mars.StepBounce:
+				r.rover.Rotate(mars.Around)
+			case mars.StepObstacle:
+				if s.Grid.ObstaclePolicy == mars.ObstacleStop {
+					obstacle := step.To
+					r.result.Obstacle = &obstacle
+					r.stop(&mars.ObstacleError{Position: step.To})
+				} else {
+					r.skipRest()
+				}
+			}
+		}
+	}
+
+	s.resolve(moves, falls)
+
+	var scents []mars.Scent
+	for _, r := range falls {
+		scent, err := r.rover.Fall(headings[r])
+		scent.Instruction = r.actions[r.next-1].instruction
+		r.result.Err = err
+		r.status = StatusLost
+		scents = append(scents, scent)
+	}
+	for _, m := range moves {
+		if !m.blocked {
+			m.rover.rover.Advance(m.to)
+		}
+	}
+
+	// Scents left this tick only take effect once every rover has moved
+	for i, scent := range scents {
+		if s.Grid.LeaveScent(scent) {
+			position := scent.Position
+			falls[i].result.ScentAdded = &position
+		}
+	}
+
+	for _, r := range rovers {
+		if r.status == StatusActive && r.next >= len(r.actions) {
+			r.status = StatusDone
+		}
+	}
+}
+
+// resolve
+// Holds back moves that would end the tick in the same cell as another rover, swap two rovers, or run into a rover
+// that is staying put, applying the grid's CollisionPolicy. Every move in a conflict is held back, so under
+// CollisionBlock neither rover contesting a cell gets it. Holding one rover back can leave a rover behind it
+// without room, so conflicts are checked until none change.
+func (s *Simulation) resolve(moves []*lockstepMove, falls []*lockstepRover) {
+	leaving := map[*mars.Rover]bool{}
+	for _, r := range falls {
+		leaving[r.rover] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, m := range moves {
+			leaving[m.rover.rover] = !m.blocked
+		}
+
+		var conflicts []*lockstepMove
+		for _, m := range moves {
+			if m.blocked {
+				continue
+			}
+			if other := s.conflict(m, moves, leaving); other != nil {
+				m.other = other
+				conflicts = append(conflicts, m)
+			}
+		}
+
+		for _, m := range conflicts {
+			rover := m.rover.rover
+			s.Grid.Publish(mars.CollidedEvent{Rover: rover, Other: m.other, Position: m.to})
+			collision := mars.Collision{Position: m.to, Other: m.other.ID}
+
+			switch s.Grid.CollisionPolicy {
+			case mars.CollisionAllow:
+				rover.Collisions = append(rover.Collisions, collision)
+				continue
+			case mars.CollisionAbort:
+				err := &mars.CollisionError{Position: m.to, Rover: rover.ID, Other: m.other.ID}
+				m.rover.result.Collision = err
+				m.rover.stop(err)
+			default:
+				rover.Collisions = append(rover.Collisions, collision)
+				m.rover.status = StatusBlocked
+				m.rover.skipRest()
+			}
+			m.blocked = true
+			changed = true
+		}
+
+		if s.Grid.CollisionPolicy == mars.CollisionAllow {
+			return
+		}
+	}
+}
+
+// conflict
+// Returns the rover a move conflicts with, or nil if it can go ahead.
+func (s *Simulation) conflict(m *lockstepMove, moves []*lockstepMove, leaving map[*mars.Rover]bool) *mars.Rover {
+	rover := m.rover.rover
+	for _, other := range moves {
+		if other == m || other.blocked {
+			continue
+		}
+		if other.to == m.to {
+			return other.rover.rover
+		}
+		if other.to == rover.Position && m.to == other.rover.rover.Position {
+			return other.rover.rover
+		}
+	}
+
+	for _, occupant := range s.Grid.Occupants() {
+		if occupant != rover && occupant.Position == m.to && !leaving[occupant] {
+			return occupant
+		}
+	}
+	return nil
+}
+
+func anyRunning(rovers []*lockstepRover) bool {
+	for _, r := range rovers {
+		if r.running() {
+			return true
+		}
+	}
+	return false
+}
+
+func snapshot(number int, rovers []*lockstepRover) Tick {
+	tick := Tick{Number: number, Poses: make([]TickPose, 0, len(rovers))}
+	for _, r := range rovers {
+		tick.Poses = append(tick.Poses, TickPose{
+			Number:    r.result.Number,
+			Position:  r.rover.Position.Copy(),
+			Direction: r.rover.Direction,
+			Status:    r.status,
+		})
+	}
+	return tick
+}
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"errors"
+	"marster-bot/mars"
+	"strings"
+	"testing"
+)
+
+func TestSimulationRunLockstep(t *testing.T) {
+	tests := []struct {
+		name     string
+		rovers   []*mars.Rover
+		expected []string
+	}{
+		{
+			name:     "Rovers heading for the same cell from different sides are both blocked",
+			rovers:   []*mars.Rover{mars.NewRover(1, 1, mars.East, nil), mars.NewRover(2, 0, mars.North, nil)},
+			expected: []string{"1 1 E", "2 0 N"},
+		},
+		{
+			name:     "Rovers heading for the same cell are both blocked",
+			rovers:   []*mars.Rover{mars.NewRover(1, 0, mars.East, nil), mars.NewRover(3, 0, mars.West, nil)},
+			expected: []string{"1 0 E", "3 0 W"},
+		},
+		{
+			name:     "Rovers swapping cells are both blocked",
+			rovers:   []*mars.Rover{mars.NewRover(1, 0, mars.East, nil), mars.NewRover(2, 0, mars.West, nil)},
+			expected: []string{"1 0 E", "2 0 W"},
+		},
+		{
+			name:     "A rover can follow one that moves away",
+			rovers:   []*mars.Rover{mars.NewRover(0, 0, mars.East, nil), mars.NewRover(1, 0, mars.East, nil)},
+			expected: []string{"1 0 E", "2 0 E"},
+		},
+		{
+			name: "A blocked rover holds back the rover behind it",
+			rovers: []*mars.Rover{
+				mars.NewRover(0, 0, mars.East, nil), mars.NewRover(1, 0, mars.East, nil), mars.NewRover(3, 0, mars.West, nil),
+			},
+			expected: []string{"0 0 E", "1 0 E", "3 0 W"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newTestSimulation(5, 5)
+			for _, rover := range tt.rovers {
+				rover.Grid = sim.Grid
+				sim.AddRover(rover, []mars.Instruction{forward()})
+			}
+
+			results, ticks := mustRunLockstep(t, sim)
+			for i, want := range tt.expected {
+				if results[i].String() != want {
+					t.Errorf("Expected rover #%d at %s, got %s", i+1, want, results[i])
+				}
+			}
+			if len(ticks) != 2 {
+				t.Errorf("Expected a starting tick and one move, got %d ticks", len(ticks))
+			}
+		})
+	}
+
+	t.Run("Scents only protect rovers from the next tick", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+		sim.Grid.CollisionPolicy = mars.CollisionAllow
+		sim.AddRover(mars.NewRover(3, 3, mars.North, sim.Grid), []mars.Instruction{forward()})
+		sim.AddRover(mars.NewRover(3, 3, mars.North, sim.Grid), []mars.Instruction{forward()})
+		sim.AddRover(mars.NewRover(2, 3, mars.East, sim.Grid), []mars.Instruction{forward(), left(), forward()})
+
+		results, ticks := mustRunLockstep(t, sim)
+		if !results[0].Lost || !results[1].Lost {
+			t.Errorf("Expected both rovers falling on the same tick to be lost, got %s and %s", results[0], results[1])
+		}
+		if results[0].ScentAdded == nil || results[1].ScentAdded != nil {
+			t.Errorf("Expected only rover #1 to be credited with the scent")
+		}
+		if results[2].Lost || results[2].String() != "3 3 N" {
+			t.Errorf("Expected rover #3 to be protected by the scent on a later tick, got %s", results[2])
+		}
+		if pose := ticks[1].Poses[0]; pose.Status != StatusLost {
+			t.Errorf("Expected rover #1 to be lost on tick 1, got %s", pose.Status)
+		}
+		if ticks[1].Poses[2].Position != mars.NewPosition(3, 3) {
+			t.Errorf("Expected rover #3 to move into the cell left by the falling rovers, got %v", ticks[1].Poses[2].Position)
+		}
+	})
+
+	t.Run("Multi-space moves take one tick per space", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.AddRover(mars.NewRover(0, 0, mars.North, sim.Grid), []mars.Instruction{mars.NewMovementInstruction(3), right()})
+
+		results, ticks := mustRunLockstep(t, sim)
+		if len(ticks) != 5 {
+			t.Fatalf("Expected 5 ticks, got %d", len(ticks))
+		}
+		if ticks[2].String() != "tick 2: #1 0 2 N" {
+			t.Errorf("Unexpected timeline line: %q", ticks[2].String())
+		}
+		if ticks[4].String() != "tick 4: #1 0 3 E done" {
+			t.Errorf("Unexpected timeline line: %q", ticks[4].String())
+		}
+		if results[0].String() != "0 3 E" {
+			t.Errorf("Expected 0 3 E, got %s", results[0])
+		}
+
+		record := ticks[4].Record()
+		if record.Tick != 4 || len(record.Rovers) != 1 || record.Rovers[0].Status != StatusDone {
+			t.Errorf("Unexpected tick record: %+v", record)
+		}
+	})
+
+	t.Run("Abort policy stops conflicting rovers", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.Grid.CollisionPolicy = mars.CollisionAbort
+		sim.AddRover(mars.NewRover(1, 0, mars.East, sim.Grid), []mars.Instruction{forward(), forward()})
+		sim.AddRover(mars.NewRover(3, 0, mars.West, sim.Grid), []mars.Instruction{forward(), forward()})
+
+		results, ticks := mustRunLockstep(t, sim)
+		for _, result := range results {
+			if result.Collision == nil {
+				t.Errorf("Expected rover #%d to be aborted, got %s", result.Number, result)
+			}
+		}
+		if len(ticks) != 2 || ticks[1].Poses[0].Status != StatusStopped {
+			t.Errorf("Expected both rovers to stop on tick 1, got %v", ticks)
+		}
+	})
+
+	t.Run("Sensing decides at the start of each tick", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.Grid.AddObstacle(mars.NewPosition(3, 0))
+		program := []mars.Instruction{
+			&mars.LoopInstruction{Until: mars.ConditionBlocked, Body: []mars.Instruction{forward()}},
+			&mars.ConditionalInstruction{Condition: mars.ConditionBlocked, Then: []mars.Instruction{right()}},
+		}
+		sim.AddRover(mars.NewRover(0, 0, mars.East, sim.Grid), program)
+
+		results, ticks := mustRunLockstep(t, sim)
+		if results[0].String() != "2 0 S" {
+			t.Errorf("Expected 2 0 S, got %s", results[0])
+		}
+		// Two moves and a turn, with the decisions taking no tick of their own
+		if len(ticks) != 4 || ticks[3].String() != "tick 3: #1 2 0 S done" {
+			t.Errorf("Expected three ticks ending with the turn, got %v", ticks)
+		}
+	})
+
+	t.Run("A rover can stop in front of another that moves away", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		wait := &mars.LoopInstruction{Until: mars.ConditionClear, Body: []mars.Instruction{right(), right(), right(), right()}}
+		sim.AddRover(mars.NewRover(1, 0, mars.East, sim.Grid), []mars.Instruction{forward()})
+		sim.AddRover(mars.NewRover(0, 0, mars.East, sim.Grid), []mars.Instruction{wait, forward()})
+
+		results, _ := mustRunLockstep(t, sim)
+		if results[1].String() != "1 0 E" {
+			t.Errorf("Expected the second rover to wait then follow to 1 0 E, got %s", results[1])
+		}
+	})
+
+	t.Run("Loop limit stops the rover", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		spin := &mars.LoopInstruction{Until: mars.ConditionOffGrid, Body: []mars.Instruction{right()}}
+		sim.AddRover(mars.NewRover(2, 2, mars.North, sim.Grid), []mars.Instruction{spin})
+
+		results, ticks := mustRunLockstep(t, sim)
+		if results[0].Loop == nil {
+			t.Errorf("Expected a loop limit, got %s", results[0])
+		}
+		if last := ticks[len(ticks)-1]; last.Poses[0].Status != StatusStopped {
+			t.Errorf("Expected the rover to be stopped, got %s", last)
+		}
+	})
+
+	t.Run("Both rovers contesting a cell are blocked and record the collision", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.AddRover(mars.NewRover(1, 0, mars.East, sim.Grid), []mars.Instruction{forward(), left()})
+		sim.AddRover(mars.NewRover(3, 0, mars.West, sim.Grid), []mars.Instruction{forward(), left()})
+
+		results, ticks := mustRunLockstep(t, sim)
+		if ticks[1].String() != "tick 1: #1 1 0 E blocked | #2 3 0 W blocked" {
+			t.Errorf("Unexpected timeline line: %q", ticks[1].String())
+		}
+		for i, result := range results {
+			other := 2 - i
+			if len(result.Collisions) != 1 || result.Collisions[0].Other != other || result.Collision != nil {
+				t.Errorf("Expected rover #%d to be blocked by rover #%d, got %v", result.Number, other, result.Collisions)
+			}
+		}
+		if results[0].String() != "1 0 N" || results[1].String() != "3 0 S" {
+			t.Errorf("Expected both rovers to carry on after being blocked, got %s and %s", results[0], results[1])
+		}
+	})
+
+	t.Run("Registered instructions are rejected", func(t *testing.T) {
+		err := mars.RegisterInstruction(mars.InstructionKind{
+			Name:        "dash",
+			Instruction: &dashInstruction{},
+			Execute: func(rover *mars.Rover, instruction mars.Instruction) error {
+				return rover.Move(2)
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer mars.UnregisterInstruction("dash")
+
+		sim := newTestSimulation(5, 3)
+		sim.AddRover(mars.NewRover(0, 0, mars.North, sim.Grid), []mars.Instruction{right()})
+		loop := &mars.LoopInstruction{Until: mars.ConditionBlocked, Body: []mars.Instruction{&dashInstruction{}}}
+		sim.AddRover(mars.NewRover(4, 2, mars.North, sim.Grid), []mars.Instruction{loop})
+
+		_, _, err = sim.RunLockstep()
+		if !errors.Is(err, ErrNotLockstep) || !strings.Contains(err.Error(), "rover #2") {
+			t.Errorf("Expected rover #2's dash to be rejected, got %v", err)
+		}
+		if sim.Pending() != 2 || sim.Grid.OccupantAt(mars.NewPosition(0, 0), nil) != nil {
+			t.Errorf("Expected nothing to run, got %d rovers queued", sim.Pending())
+		}
+	})
+}
+
+func mustRunLockstep(t *testing.T, sim *Simulation) ([]*RoverResult, []Tick) {
+	t.Helper()
+	results, ticks, err := sim.RunLockstep()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return results, ticks
+}
+
+// dashInstruction
+// Moves two spaces at once, standing in for an instruction registered by another package.
+type dashInstruction struct {
+	mars.InstructionBase
+}
+
+func (d *dashInstruction) String() string {
+	return "Dash"
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"fmt"
+	"marster-bot/mars"
+	"math"
+)
+
+// Optimization
+// A program rewritten by Optimize, with the number of instructions before and after, counted like the instruction
+// limit, including those inside conditionals and loops.
+type Optimization struct {
+	Instructions []mars.Instruction
+	Before       int
+	After        int
+}
+
+// Saved
+// Returns how many instructions the optimization removed.
+func (o *Optimization) Saved() int {
+	return o.Before - o.After
+}
+
+func (o *Optimization) String() string {
+	if o.Saved() == 0 {
+		return fmt.Sprintf("%d instructions, nothing to optimize", o.Before)
+	}
+	return fmt.Sprintf("saved %d of %d instructions: %s", o.Saved(), o.Before, Program(o.Instructions))
+}
+
+// Optimize
+// Rewrites a program to do the same in fewer instructions: consecutive rotations are folded into the single turn
+// they amount to, or dropped when they cancel out (RRRR, LR), and consecutive moves in the same direction are merged
+// into multi-space moves of up to 127 spaces. Conditional and loop bodies are optimized on their own, and nothing is
+// folded across them or across registered instructions. Run one after another on the same grid, the optimized
+// program leaves the rover in the same pose as the original; in lock-step runs the timing of turns may differ. The
+// original instructions are left as they are.
+func Optimize(instructions []mars.Instruction) *Optimization {
+	optimized := optimize(instructions)
+	return &Optimization{
+		Instructions: optimized,
+		Before:       mars.InstructionCount(instructions),
+		After:        mars.InstructionCount(optimized),
+	}
+}
+
+func optimize(instructions []mars.Instruction) []mars.Instruction {
+	optimized := []mars.Instruction{}
+
+	for _, instruction := range instructions {
+		var last mars.Instruction
+		if len(optimized) > 0 {
+			last = optimized[len(optimized)-1]
+		}
+
+		switch instruction := instruction.(type) {
+		case *mars.RotationInstruction:
+			quarters := quarterTurns(instruction.Orientation)
+			if previous, ok := last.(*mars.RotationInstruction); ok {
+				quarters += quarterTurns(previous.Orientation)
+				optimized = optimized[:len(optimized)-1]
+			}
+			if rotation, ok := rotationFor(quarters); ok {
+				optimized = append(optimized, mars.NewOrientationInstruction(rotation))
+			}
+		case *mars.MovementInstruction:
+			distance := int(instruction.Distance)
+			if distance == 0 {
+				continue
+			}
+			if previous, ok := last.(*mars.MovementInstruction); ok && (previous.Distance < 0) == (distance < 0) {
+				distance = mergeDistance(previous, distance)
+			}
+			if distance != 0 {
+				optimized = append(optimized, mars.NewMovementInstruction(int8(distance)))
+			}
+		case *mars.ConditionalInstruction:
+			then, otherwise := optimize(instruction.Then), optimize(instruction.Else)
+			if len(then) == 0 && len(otherwise) == 0 {
+				// Sensing has no effect of its own, so a conditional that does nothing either way can go
+				continue
+			}
+			if len(then) == 0 {
+				// A conditional cannot be written with an empty first block, so it is kept as it was
+				then = instruction.Then
+			}
+			optimized = append(optimized, &mars.ConditionalInstruction{Condition: instruction.Condition, Then: then, Else: otherwise})
+		case *mars.LoopInstruction:
+			body := optimize(instruction.Body)
+			if len(body) == 0 {
+				// A loop cannot be written with an empty body, so it is kept as it was
+				body = instruction.Body
+			}
+			optimized = append(optimized, &mars.LoopInstruction{Until: instruction.Until, Body: body})
+		default:
+			optimized = append(optimized, instruction)
+		}
+	}
+
+	return optimized
+}
+
+// quarterTurns
+// Returns the rotation as a number of quarter turns to the right.
+func quarterTurns(rotation mars.Rotation) int {
+	switch rotation {
+	case mars.Right:
+		return 1
+	case mars.Around:
+		return 2
+	case mars.Left:
+		return 3
+	}
+	return 0
+}
+
+// rotationFor
+// Returns the single rotation that makes the given number of quarter turns to the right, or false if they make a
+// full turn and no rotation is needed.
+func rotationFor(quarters int) (mars.Rotation, bool) {
+	switch quarters % 4 {
+	case 1:
+		return mars.Right, true
+	case 2:
+		return mars.Around, true
+	case 3:
+		return mars.Left, true
+	}
+	return 0, false
+}
+
+// mergeDistance
+// Adds as much of the distance to the previous move, which is already in the optimized program, as a move can hold,
+// and returns what is left over for a move of its own.
+func mergeDistance(previous *mars.MovementInstruction, distance int) int {
+	sign := 1
+	if distance < 0 {
+		sign = -1
+	}
+
+	room := math.MaxInt8 - sign*int(previous.Distance)
+	taken := min(room, sign*distance)
+	previous.Distance += int8(sign * taken)
+	return distance - sign*taken
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		before int
+		after  int
+	}{
+		{"Full turn", "RRRR", "", 4, 0},
+		{"Rotations that cancel", "LR", "", 2, 0},
+		{"Three rights", "RRR", "L", 3, 1},
+		{"Two lefts", "LL", "T", 2, 1},
+		{"Turn around and right", "TR", "L", 2, 1},
+		{"Moves", "FFF", "F3", 3, 1},
+		{"Moves after a cancelled turn", "F RL F2", "F3", 4, 1},
+		{"Backward moves", "BB2", "B3", 2, 1},
+		{"Opposite moves are kept", "FB", "FB", 2, 2},
+		{"Long moves", "F100 F100 F100", "F127F127F46", 3, 3},
+		{"Nothing to optimize", "FRFLB", "FRFLB", 5, 5},
+		{"Conditional branches", "if blocked (RRR) else (FF) F", "IF BLOCKED (L) ELSE (F2)F", 7, 4},
+		{"Nothing folded across a conditional", "F if clear (F) F", "FIF CLEAR (F)F", 4, 4},
+		{"Conditional that does nothing", "R if clear (LR) else (RRRR) L", "", 9, 0},
+		{"Empty first branch is kept", "if clear (LR) else (FF)", "IF CLEAR (LR) ELSE (F2)", 5, 4},
+		{"Loop body", "until blocked (FF)", "UNTIL BLOCKED (F2)", 3, 2},
+		{"Empty loop body is kept", "until blocked (LR)", "UNTIL BLOCKED (LR)", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instructions, err := input.ParseProgram(tt.input, input.Macros{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			original := Program(instructions)
+
+			optimization := Optimize(instructions)
+			if got := Program(optimization.Instructions); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if optimization.Before != tt.before || optimization.After != tt.after {
+				t.Errorf("Expected %d -> %d instructions, got %d -> %d", tt.before, tt.after, optimization.Before, optimization.After)
+			}
+			if optimization.Saved() != tt.before-tt.after {
+				t.Errorf("Expected %d saved, got %d", tt.before-tt.after, optimization.Saved())
+			}
+			if got := Program(instructions); got != original {
+				t.Errorf("Expected the original program to be left as %q, got %q", original, got)
+			}
+		})
+	}
+
+	t.Run("Reports the savings", func(t *testing.T) {
+		optimization := Optimize([]mars.Instruction{right(), right(), right(), forward()})
+		if got := optimization.String(); got != "saved 2 of 4 instructions: LF" {
+			t.Errorf("Expected the savings, got %q", got)
+		}
+		if got := Optimize([]mars.Instruction{forward()}).String(); got != "1 instructions, nothing to optimize" {
+			t.Errorf("Expected nothing to optimize, got %q", got)
+		}
+	})
+}
+
+// randomProgram
+// Builds a program of moves, rotations and the occasional conditional or loop, biased towards runs of the same
+// instruction so there is plenty to optimize.
+func randomProgram(random *rand.Rand, length int, depth int) []mars.Instruction {
+	conditions := []mars.Condition{mars.ConditionOffGrid, mars.ConditionScented, mars.ConditionBlocked, mars.ConditionClear}
+	rotations := []mars.Rotation{mars.Right, mars.Left, mars.Around}
+
+	var program []mars.Instruction
+	for range length {
+		switch choice := random.IntN(10); {
+		case choice < 4:
+			distance := int8(1 + random.IntN(3))
+			if random.IntN(4) == 0 {
+				distance = -distance
+			}
+			program = append(program, mars.NewMovementInstruction(distance))
+		case choice < 8:
+			program = append(program, mars.NewOrientationInstruction(rotations[random.IntN(len(rotations))]))
+		case choice < 9 && depth > 0:
+			program = append(program, &mars.ConditionalInstruction{
+				Condition: conditions[random.IntN(len(conditions))],
+				Then:      randomProgram(random, 1+random.IntN(4), depth-1),
+				Else:      randomProgram(random, random.IntN(4), depth-1),
+			})
+		case depth > 0:
+			program = append(program, &mars.LoopInstruction{
+				Until: conditions[random.IntN(len(conditions))],
+				Body:  randomProgram(random, 1+random.IntN(4), depth-1),
+			})
+		}
+	}
+	return program
+}
+
+func TestOptimizeKeepsFinalPose(t *testing.T) {
+	random := rand.New(rand.NewPCG(24, 1))
+	edges := []mars.EdgePolicy{mars.EdgeLost, mars.EdgeClamp, mars.EdgeBounce, mars.EdgeWrap}
+	obstacles := []mars.ObstaclePolicy{mars.ObstacleSkip, mars.ObstacleStop}
+
+	// run
+	// Runs the program for a rover on a fresh grid set up the same way each time.
+	run := func(edge mars.EdgePolicy, obstacle mars.ObstaclePolicy, seed uint64, program []mars.Instruction) string {
+		setup := rand.New(rand.NewPCG(seed, 2))
+		sim := newTestSimulation(6, 4)
+		sim.Grid.EdgePolicy = edge
+		sim.Grid.ObstaclePolicy = obstacle
+		for range 3 {
+			sim.Grid.AddObstacle(mars.NewPosition(int8(setup.IntN(7)), int8(setup.IntN(5))))
+		}
+		sim.Grid.AddScent(mars.NewPosition(int8(setup.IntN(7)), 4), mars.North)
+
+		// A parked rover to run into
+		sim.AddRover(mars.NewRover(int8(setup.IntN(7)), int8(setup.IntN(5)), mars.South, sim.Grid), nil)
+		start := mars.NewPosition(int8(setup.IntN(7)), int8(setup.IntN(5)))
+		sim.AddRover(mars.NewRover(start.X, start.Y, mars.East, sim.Grid), program)
+		return sim.Run()[1].String()
+	}
+
+	for i := range 500 {
+		edge, obstacle := edges[i%len(edges)], obstacles[(i/len(edges))%len(obstacles)]
+		program := randomProgram(random, 5+random.IntN(30), 2)
+		optimized := Optimize(program).Instructions
+
+		t.Run(fmt.Sprintf("%d %s %s", i, edge, obstacle), func(t *testing.T) {
+			want := run(edge, obstacle, uint64(i), program)
+			if got := run(edge, obstacle, uint64(i), optimized); got != want {
+				t.Errorf("%s optimized to %s: expected %s, got %s", Program(program), Program(optimized), want, got)
+			}
+		})
+	}
+}
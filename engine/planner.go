@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"marster-bot/mars"
+)
+
+// ErrNoRoute
+// Returned by Plan when every route to the target would lose the rover or is blocked.
+var ErrNoRoute = errors.New("no safe route")
+
+// Target
+// Where a planned route should end: a cell, and optionally the direction the rover should face there.
+type Target struct {
+	Position  mars.Position
+	Direction *mars.Direction
+}
+
+func (t Target) String() string {
+	if t.Direction == nil {
+		return t.Position.String()
+	}
+	return fmt.Sprintf("%s %s", t.Position.String(), t.Direction)
+}
+
+// pose
+// A node in the planner's search: a cell and the direction the rover faces in it.
+type pose struct {
+	position  mars.Position
+	direction mars.Direction
+}
+
+// route
+// How the planner first reached a pose: the pose it came from and the instruction that led here.
+type route struct {
+	from        pose
+	instruction mars.Instruction
+}
+
+// alphabet
+// The instructions the planner may use, in the order they are tried. Single steps keep every cell on the route
+// checked; consecutive moves are merged into multi-space moves once the route is found, which does not change where
+// the rover ends up.
+var alphabet = []mars.Instruction{
+	mars.NewMovementInstruction(1),
+	mars.NewMovementInstruction(-1),
+	mars.NewOrientationInstruction(mars.Left),
+	mars.NewOrientationInstruction(mars.Right),
+	mars.NewOrientationInstruction(mars.Around),
+}
+
+// Plan
+// Finds the shortest instruction sequence that takes the rover from its current pose to the target without being
+// lost, using a breadth-first search over (position, heading). Routes follow the grid's edge policy, so they may
+// wrap or bounce at the edges, and never pass through obstacles or cells held by other rovers. Moves that a scent,
+// clamp or obstacle would ignore are never planned. Consecutive moves are merged, so a straight run of nine cells is
+// planned as F9 rather than FFFFFFFFF. Returns no instructions if the rover is already at the target,
+// and ErrNoRoute if the target cannot be reached safely.
+func Plan(grid *mars.Grid, rover *mars.Rover, target Target) ([]mars.Instruction, error) {
+	if !grid.PositionWithinBoundsXY(target.Position.X, target.Position.Y) {
+		return nil, fmt.Errorf("target %s is outside grid bounds (0-%d,0-%d)", target.Position.String(), grid.XSize, grid.YSize)
+	}
+
+	start := pose{position: rover.Position, direction: rover.Direction}
+	routes := map[pose]route{start: {}}
+	queue := []pose{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if reached(current, target) {
+			return optimize(retrace(routes, start, current)), nil
+		}
+
+		for _, instruction := range alphabet {
+			next, ok := follow(grid, rover, current, instruction)
+			if !ok {
+				continue
+			}
+			if _, seen := routes[next]; seen {
+				continue
+			}
+			routes[next] = route{from: current, instruction: instruction}
+			queue = append(queue, next)
+		}
+	}
+
+	return nil, fmt.Errorf("%w from %s %s to %s", ErrNoRoute, start.position.String(), start.direction, target)
+}
+
+// follow
+// Works out the pose an instruction leads to, without moving the rover. Reports false for instructions that would
+// lose the rover, run into another rover, or leave it where it was.
+func follow(grid *mars.Grid, rover *mars.Rover, from pose, instruction mars.Instruction) (pose, bool) {
+	switch instruction := instruction.(type) {
+	case *mars.RotationInstruction:
+		return pose{position: from.position, direction: from.direction.Rotate(instruction.Orientation)}, true
+	case *mars.MovementInstruction:
+		heading := from.direction
+		if instruction.Distance < 0 {
+			heading = heading.Rotate(mars.Around)
+		}
+
+		probe := &mars.Rover{Position: from.position, Direction: from.direction, Grid: grid}
+		step := probe.PlanStep(heading)
+		switch step.Kind {
+		case mars.StepMove, mars.StepWrap:
+			if grid.OccupantAt(step.To, rover) != nil {
+				return pose{}, false
+			}
+			return pose{position: step.To, direction: from.direction}, true
+		case mars.StepBounce:
+			return pose{position: from.position, direction: from.direction.Rotate(mars.Around)}, true
+		}
+	}
+	return pose{}, false
+}
+
+func reached(current pose, target Target) bool {
+	if current.position != target.Position {
+		return false
+	}
+	return target.Direction == nil || current.direction == *target.Direction
+}
+
+// retrace
+// Walks the routes back from the end pose to the start, returning the instructions in the order they run.
+func retrace(routes map[pose]route, start, end pose) []mars.Instruction {
+	var instructions []mars.Instruction
+	for current := end; current != start; current = routes[current].from {
+		instructions = append(instructions, routes[current].instruction)
+	}
+	for i, j := 0, len(instructions)-1; i < j; i, j = i+1, j-1 {
+		instructions[i], instructions[j] = instructions[j], instructions[i]
+	}
+	return instructions
+}
+
+// Program
+// Formats instructions as the string an operator would type, e.g. 'RFFLF'.
+func Program(instructions []mars.Instruction) string {
+	return mars.ProgramCode(instructions)
+}
@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"errors"
+	"marster-bot/mars"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	north, west := mars.North, mars.West
+
+	tests := []struct {
+		name     string
+		setup    func(grid *mars.Grid)
+		start    *mars.Rover
+		target   Target
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "Already at the target",
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(1, 1)},
+			expected: "",
+		},
+		{
+			name:     "Straight ahead",
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(1, 3)},
+			expected: "F2",
+		},
+		{
+			name:     "Reverses rather than turning around",
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(1, 0)},
+			expected: "B",
+		},
+		{
+			name:     "Turns to face the target direction",
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(0, 1), Direction: &west},
+			expected: "LF",
+		},
+		{
+			name:     "Goes around an obstacle",
+			setup:    func(grid *mars.Grid) { grid.AddObstacle(mars.NewPosition(1, 2)) },
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(1, 3), Direction: &north},
+			expected: "LFLB2LFL",
+		},
+		{
+			name: "Goes around a parked rover",
+			setup: func(grid *mars.Grid) {
+				grid.Occupy(mars.NewRover(1, 2, mars.South, grid))
+			},
+			start:    mars.NewRover(1, 1, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(1, 3), Direction: &north},
+			expected: "LFLB2LFL",
+		},
+		{
+			name:     "Merges a long straight run",
+			setup:    func(grid *mars.Grid) { grid.YSize = 9 },
+			start:    mars.NewRover(0, 0, mars.North, nil),
+			target:   Target{Position: mars.NewPosition(0, 9)},
+			expected: "F9",
+		},
+		{
+			name: "Crosses the edge when it wraps",
+			setup: func(grid *mars.Grid) {
+				grid.EdgePolicy = mars.EdgeWrap
+			},
+			start:    mars.NewRover(0, 0, mars.West, nil),
+			target:   Target{Position: mars.NewPosition(5, 0)},
+			expected: "F",
+		},
+		{
+			name: "Never leaves the grid",
+			setup: func(grid *mars.Grid) {
+				grid.AddObstacle(mars.NewPosition(1, 0))
+				grid.AddObstacle(mars.NewPosition(0, 1))
+			},
+			start:   mars.NewRover(0, 0, mars.North, nil),
+			target:  Target{Position: mars.NewPosition(3, 3)},
+			wantErr: ErrNoRoute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := mars.NewGrid(5, 3)
+			if tt.setup != nil {
+				tt.setup(grid)
+			}
+			tt.start.Grid = grid
+
+			instructions, err := Plan(grid, tt.start, tt.target)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if program := Program(instructions); program != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, program)
+			}
+		})
+	}
+
+	t.Run("Planned routes arrive without losing the rover", func(t *testing.T) {
+		grid := mars.NewGrid(5, 3)
+		grid.AddScent(mars.NewPosition(5, 3), mars.North)
+		grid.AddObstacle(mars.NewPosition(2, 2))
+
+		for x := range int8(6) {
+			for y := range int8(4) {
+				if grid.IsObstacle(mars.NewPosition(x, y)) {
+					continue
+				}
+				rover := mars.NewRover(0, 0, mars.North, grid)
+				instructions, err := Plan(grid, rover, Target{Position: mars.NewPosition(x, y)})
+				if err != nil {
+					t.Fatalf("Unexpected error planning to (%d,%d): %v", x, y, err)
+				}
+				for _, instruction := range instructions {
+					if err := rover.Instruct(instruction); err != nil {
+						t.Fatalf("Route %s to (%d,%d) failed: %v", Program(instructions), x, y, err)
+					}
+				}
+				if rover.Lost || rover.Position != mars.NewPosition(x, y) {
+					t.Errorf("Route %s to (%d,%d) ended at %s", Program(instructions), x, y, rover.Position.String())
+				}
+			}
+		}
+	})
+}
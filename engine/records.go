@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"errors"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"sort"
+)
+
+// GridRecord
+// Describes the grid's size, policies, obstacles and scents for machine-readable output.
+func GridRecord(grid *mars.Grid) output.GridRecord {
+	return output.GridRecord{
+		MaxX:            int(grid.XSize),
+		MaxY:            int(grid.YSize),
+		ObstaclePolicy:  grid.ObstaclePolicy.String(),
+		CollisionPolicy: grid.CollisionPolicy.String(),
+		EdgePolicy:      grid.EdgePolicy.String(),
+		ScentMode:       grid.ScentMode.String(),
+		Obstacles:       pointRecords(grid.Obstacles()),
+		Scents:          pointRecords(grid.Scents()),
+	}
+}
+
+// Map
+// Describes the grid and the trail of every rover run so far for drawing.
+func (s *Simulation) Map() output.GridMap {
+	gridMap := output.GridMap{
+		Width:     int(s.Grid.XSize) + 1,
+		Height:    int(s.Grid.YSize) + 1,
+		Edge:      s.Grid.EdgePolicy.String(),
+		Scents:    pointRecords(s.Grid.Scents()),
+		Obstacles: pointRecords(s.Grid.Obstacles()),
+	}
+
+	for _, result := range s.results {
+		trail := output.TrailRecord{
+			Number: result.Number,
+			Final:  poseRecord(result.Position, result.Direction),
+			Lost:   result.Lost,
+		}
+		for _, position := range result.Trail {
+			trail.Points = append(trail.Points, output.PointRecord{X: int(position.X), Y: int(position.Y)})
+		}
+		gridMap.Trails = append(gridMap.Trails, trail)
+	}
+
+	return gridMap
+}
+
+// Record
+// Describes every rover's pose at the end of the tick for machine-readable output.
+func (t Tick) Record() output.TickRecord {
+	record := output.TickRecord{Tick: t.Number, Rovers: make([]output.TickPoseRecord, 0, len(t.Poses))}
+	for _, pose := range t.Poses {
+		record.Rovers = append(record.Rovers, output.TickPoseRecord{
+			Number:    pose.Number,
+			X:         int(pose.Position.X),
+			Y:         int(pose.Position.Y),
+			Direction: pose.Direction.String(),
+			Status:    pose.Status,
+		})
+	}
+	return record
+}
+
+// Record
+// Describes the rover's run for machine-readable output.
+func (r *RoverResult) Record() output.RoverRecord {
+	record := output.RoverRecord{
+		Number:       r.Number,
+		Start:        poseRecord(r.StartPosition, r.StartDirection),
+		Instructions: make([]string, 0, len(r.Instructions)),
+		Final:        poseRecord(r.Position, r.Direction),
+		Lost:         r.Lost,
+		ScentAdded:   pointRecord(r.ScentAdded),
+		Obstacle:     pointRecord(r.Obstacle),
+		Collisions:   make([]output.CollisionRecord, 0, len(r.Collisions)),
+	}
+
+	for _, instruction := range r.Instructions {
+		record.Instructions = append(record.Instructions, mars.InstructionCode(instruction))
+	}
+
+	for _, collision := range r.Collisions {
+		record.Collisions = append(record.Collisions, output.CollisionRecord{
+			X:     int(collision.Position.X),
+			Y:     int(collision.Position.Y),
+			Rover: r.Number,
+			Other: collision.Other,
+		})
+	}
+	if r.Collision != nil {
+		record.Collisions = append(record.Collisions, output.CollisionRecord{
+			X:     int(r.Collision.Position.X),
+			Y:     int(r.Collision.Position.Y),
+			Rover: r.Collision.Rover,
+			Other: r.Collision.Other,
+		})
+	}
+
+	if r.Err != nil {
+		message := r.Err.Error()
+		kind := errorKind(r.Err)
+		record.Error = &message
+		record.ErrorKind = &kind
+	}
+
+	return record
+}
+
+// Record
+// Describes the dry run for machine-readable output.
+func (r *CheckReport) Record() output.CheckRecord {
+	record := output.CheckRecord{
+		Schema:       output.SchemaVersion,
+		Start:        poseRecord(r.StartPosition, r.StartDirection),
+		Instructions: make([]string, 0, len(r.Instructions)),
+		Final:        poseRecord(r.Position, r.Direction),
+		Lost:         r.Lost,
+		Ignored:      make([]output.IgnoredMoveRecord, 0, len(r.Ignored)),
+	}
+
+	for _, instruction := range r.Instructions {
+		record.Instructions = append(record.Instructions, mars.InstructionCode(instruction))
+	}
+
+	if r.Lost {
+		lostAt := r.LostAt
+		record.LostAt = &lostAt
+	}
+
+	for _, ignored := range r.Ignored {
+		record.Ignored = append(record.Ignored, output.IgnoredMoveRecord{
+			Index:       ignored.Index,
+			Instruction: mars.InstructionCode(ignored.Instruction),
+			X:           int(ignored.Position.X),
+			Y:           int(ignored.Position.Y),
+			Direction:   ignored.Direction.String(),
+		})
+	}
+
+	if r.Err != nil {
+		message := r.Err.Error()
+		kind := errorKind(r.Err)
+		record.Error = &message
+		record.ErrorKind = &kind
+	}
+
+	return record
+}
+
+// errorKind
+// Classifies a rover's error so machine-readable output can branch on the kind of failure.
+func errorKind(err error) string {
+	var lostErr *mars.LostError
+	var obstacleErr *mars.ObstacleError
+	var collisionErr *mars.CollisionError
+	var loopErr *mars.LoopLimitError
+
+	switch {
+	case errors.As(err, &lostErr):
+		return output.ErrorKindLost
+	case errors.As(err, &obstacleErr):
+		return output.ErrorKindObstacle
+	case errors.As(err, &collisionErr):
+		return output.ErrorKindCollision
+	case errors.Is(err, mars.ErrUnknownInstruction):
+		return output.ErrorKindUnknownInstruction
+	case errors.As(err, &loopErr):
+		return output.ErrorKindLoopLimit
+	default:
+		return output.ErrorKindOther
+	}
+}
+
+func poseRecord(position mars.Position, direction mars.Direction) output.PoseRecord {
+	return output.PoseRecord{X: int(position.X), Y: int(position.Y), Direction: direction.String()}
+}
+
+func pointRecord(position *mars.Position) *output.PointRecord {
+	if position == nil {
+		return nil
+	}
+	return &output.PointRecord{X: int(position.X), Y: int(position.Y)}
+}
+
+// pointRecords
+// Converts positions to records sorted by row then column so output is stable between runs.
+func pointRecords(positions []mars.Position) []output.PointRecord {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Y != positions[j].Y {
+			return positions[i].Y < positions[j].Y
+		}
+		return positions[i].X < positions[j].X
+	})
+
+	records := make([]output.PointRecord, 0, len(positions))
+	for _, position := range positions {
+		records = append(records, output.PointRecord{X: int(position.X), Y: int(position.Y)})
+	}
+	return records
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"marster-bot/mars"
+	"marster-bot/output"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	sim := newTestSimulation(3, 3)
+	sim.Grid.AddObstacle(mars.NewPosition(2, 0))
+	sim.Grid.AddObstacle(mars.NewPosition(0, 2))
+	sim.AddRover(mars.NewRover(1, 2, mars.North, sim.Grid), []mars.Instruction{
+		right(), mars.NewMovementInstruction(-1), forward(), left(), mars.NewMovementInstruction(3),
+	})
+
+	result := sim.Run()[0]
+	record := result.Record()
+
+	wantInstructions := []string{"R", "B", "F", "L", "F3"}
+	if len(record.Instructions) != len(wantInstructions) {
+		t.Fatalf("Expected instructions %v, got %v", wantInstructions, record.Instructions)
+	}
+	for i, want := range wantInstructions {
+		if record.Instructions[i] != want {
+			t.Errorf("Expected instruction %d to be %q, got %q", i, want, record.Instructions[i])
+		}
+	}
+
+	if record.Start.X != 1 || record.Start.Y != 2 || record.Start.Direction != "N" {
+		t.Errorf("Unexpected start pose: %+v", record.Start)
+	}
+	if !record.Lost || record.ScentAdded == nil || record.ScentAdded.X != 2 || record.ScentAdded.Y != 3 {
+		t.Errorf("Expected rover lost with scent at (2,3), got lost=%v scent=%v", record.Lost, record.ScentAdded)
+	}
+	if record.Error == nil || record.ErrorKind == nil || *record.ErrorKind != output.ErrorKindLost {
+		t.Errorf("Expected the fall to be recorded as a %q error, got %v", output.ErrorKindLost, record.ErrorKind)
+	}
+
+	grid := GridRecord(sim.Grid)
+	if len(grid.Obstacles) != 2 || grid.Obstacles[0].Y != 0 || grid.Obstacles[1].Y != 2 {
+		t.Errorf("Expected obstacles sorted by row, got %v", grid.Obstacles)
+	}
+	if len(grid.Scents) != 1 {
+		t.Errorf("Expected 1 scent, got %v", grid.Scents)
+	}
+}
+
+func TestLockstepAbortRecord(t *testing.T) {
+	sim := newTestSimulation(5, 5)
+	sim.Grid.CollisionPolicy = mars.CollisionAbort
+	sim.AddRover(mars.NewRover(1, 0, mars.East, sim.Grid), []mars.Instruction{forward()})
+	sim.AddRover(mars.NewRover(3, 0, mars.West, sim.Grid), []mars.Instruction{forward()})
+
+	results, _, err := sim.RunLockstep()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, result := range results {
+		record := result.Record()
+		if len(record.Collisions) != 1 {
+			t.Errorf("Expected rover #%d to record the collision once, got %+v", result.Number, record.Collisions)
+		}
+		if record.ErrorKind == nil || *record.ErrorKind != output.ErrorKindCollision {
+			t.Errorf("Expected rover #%d to be aborted by the collision, got %v", result.Number, record.ErrorKind)
+		}
+	}
+}
+
+func TestSimulationMap(t *testing.T) {
+	sim := newTestSimulation(4, 2)
+	sim.AddRover(mars.NewRover(0, 0, mars.East, sim.Grid), []mars.Instruction{mars.NewMovementInstruction(2), left()})
+	sim.AddRover(mars.NewRover(4, 0, mars.North, sim.Grid), []mars.Instruction{mars.NewMovementInstruction(3)})
+	sim.Run()
+
+	gridMap := sim.Map()
+
+	if gridMap.Width != 5 || gridMap.Height != 3 {
+		t.Errorf("Expected a 5x3 cell map, got %dx%d", gridMap.Width, gridMap.Height)
+	}
+	if len(gridMap.Trails) != 2 {
+		t.Fatalf("Expected 2 trails, got %d", len(gridMap.Trails))
+	}
+
+	first := gridMap.Trails[0]
+	if len(first.Points) != 3 || first.Final.X != 2 || first.Final.Direction != "N" {
+		t.Errorf("Unexpected first trail: %+v", first)
+	}
+	second := gridMap.Trails[1]
+	if !second.Lost || len(second.Points) != 3 || len(gridMap.Scents) != 1 {
+		t.Errorf("Expected second rover lost after 3 cells with a scent, got %+v (scents %v)", second, gridMap.Scents)
+	}
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ScenarioMismatch
+// Something a scenario run got wrong: a rover's outcome, or the scents on the grid. Expected lists what the scenario
+// expected but did not get, and Got what it got instead.
+type ScenarioMismatch struct {
+	Subject  string
+	Expected []string
+	Got      []string
+}
+
+// ScenarioReport
+// The outcome of running a scenario: every rover's result and each way the run differed from the scenario.
+type ScenarioReport struct {
+	Name       string
+	Results    []*RoverResult
+	Mismatches []ScenarioMismatch
+}
+
+// Passed
+// Reports whether the run matched every expectation in the scenario.
+func (r *ScenarioReport) Passed() bool {
+	return len(r.Mismatches) == 0
+}
+
+// RunScenario
+// Builds the scenario's grid, runs its rovers one after another, so later rovers see the scents earlier ones leave,
+// and compares each outcome with what the scenario expects. Returns an error if the scenario itself is invalid.
+func RunScenario(scenario output.ScenarioRecord) (*ScenarioReport, error) {
+	grid, err := scenarioGrid(scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := NewSimulation(grid)
+	macros := input.Macros{}
+	for i, rover := range scenario.Rovers {
+		parsed, err := input.ParseRover(strings.ToUpper(rover.Pose), grid)
+		if err != nil {
+			return nil, fmt.Errorf("rover #%d: %w", i+1, err)
+		}
+		// A rover without instructions stays where it is, for later rovers to run into
+		instructions := []mars.Instruction{}
+		if strings.TrimSpace(rover.Instructions) != "" {
+			instructions, err = input.ParseProgram(rover.Instructions, macros)
+			if err != nil {
+				return nil, fmt.Errorf("rover #%d: %w", i+1, err)
+			}
+		}
+		simulation.AddRover(parsed, instructions)
+	}
+
+	report := &ScenarioReport{Name: scenario.Name, Results: simulation.Run()}
+	for i, result := range report.Results {
+		expected := normalizeOutcome(scenario.Rovers[i].Expected)
+		if got := result.String(); normalizeOutcome(got) != expected {
+			report.Mismatches = append(report.Mismatches, ScenarioMismatch{
+				Subject:  fmt.Sprintf("rover #%d", result.Number),
+				Expected: []string{scenario.Rovers[i].Expected},
+				Got:      []string{got},
+			})
+		}
+	}
+
+	if scenario.ExpectedScents != nil {
+		missing, unexpected := compareScents(*scenario.ExpectedScents, ScentMap(grid).Scents)
+		if len(missing) > 0 || len(unexpected) > 0 {
+			report.Mismatches = append(report.Mismatches, ScenarioMismatch{Subject: "scents", Expected: missing, Got: unexpected})
+		}
+	}
+
+	return report, nil
+}
+
+// scenarioGrid
+// Builds the grid a scenario starts on, with its policies, obstacles and scents.
+func scenarioGrid(scenario output.ScenarioRecord) (*mars.Grid, error) {
+	if scenario.MaxX < 1 || scenario.MaxY < 1 || scenario.MaxX > math.MaxInt8 || scenario.MaxY > math.MaxInt8 {
+		return nil, fmt.Errorf("grid must be between 1,1 and %d,%d (got %d,%d)",
+			math.MaxInt8, math.MaxInt8, scenario.MaxX, scenario.MaxY)
+	}
+	grid := mars.NewGrid(uint8(scenario.MaxX), uint8(scenario.MaxY))
+
+	var ok bool
+	if scenario.ObstaclePolicy != "" {
+		if grid.ObstaclePolicy, ok = mars.ObstaclePolicyFromName(scenario.ObstaclePolicy); !ok {
+			return nil, fmt.Errorf("invalid obstacle_policy '%s': must be skip or stop", scenario.ObstaclePolicy)
+		}
+	}
+	if scenario.CollisionPolicy != "" {
+		if grid.CollisionPolicy, ok = mars.CollisionPolicyFromName(scenario.CollisionPolicy); !ok {
+			return nil, fmt.Errorf("invalid collision_policy '%s': must be block, abort or allow", scenario.CollisionPolicy)
+		}
+	}
+	if scenario.EdgePolicy != "" {
+		if err := input.ParseEdge(scenario.EdgePolicy, grid); err != nil {
+			return nil, err
+		}
+	}
+	if scenario.ScentMode != "" {
+		if grid.ScentMode, ok = mars.ScentModeFromName(scenario.ScentMode); !ok {
+			return nil, fmt.Errorf("invalid scent_mode '%s': must be exit or position", scenario.ScentMode)
+		}
+	}
+
+	for _, obstacle := range scenario.Obstacles {
+		if err := input.ParseObstacle(fmt.Sprintf("%d %d", obstacle.X, obstacle.Y), grid); err != nil {
+			return nil, err
+		}
+	}
+
+	scentMap := output.ScentMapRecord{MaxX: scenario.MaxX, MaxY: scenario.MaxY, Scents: scenario.Scents}
+	if err := input.LoadScentMap(grid, scentMap); err != nil {
+		return nil, err
+	}
+
+	return grid, nil
+}
+
+// normalizeOutcome
+// Puts an outcome in a canonical form, so the case and spacing of an expectation do not matter.
+func normalizeOutcome(outcome string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(outcome), " "))
+}
+
+// compareScents
+// Returns the expected scents missing from the grid and the grid's scents that were not expected, as 'x y D'. The
+// instruction that left a scent is not compared.
+func compareScents(expected []output.ScentRecord, got []output.ScentRecord) ([]string, []string) {
+	describe := func(scents []output.ScentRecord) map[string]bool {
+		described := make(map[string]bool, len(scents))
+		for _, scent := range scents {
+			described[fmt.Sprintf("%d %d %s", scent.X, scent.Y, strings.ToUpper(scent.Direction))] = true
+		}
+		return described
+	}
+	want, have := describe(expected), describe(got)
+
+	var missing, unexpected []string
+	for scent := range want {
+		if !have[scent] {
+			missing = append(missing, scent)
+		}
+	}
+	for scent := range have {
+		if !want[scent] {
+			unexpected = append(unexpected, scent)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"marster-bot/output"
+	"strings"
+	"testing"
+)
+
+// classicScenario
+// The three rovers of the original problem, which every later rover's scent handling builds on.
+func classicScenario() output.ScenarioRecord {
+	return output.ScenarioRecord{
+		Schema: output.ScenarioSchemaVersion,
+		Name:   "Classic",
+		MaxX:   5,
+		MaxY:   3,
+		Rovers: []output.ScenarioRoverRecord{
+			{Pose: "1 1 E", Instructions: "RFRFRFRF", Expected: "1 1 E"},
+			{Pose: "3 2 N", Instructions: "FRRFLLFFRRFLL", Expected: "3 3 N LOST"},
+			{Pose: "0 3 W", Instructions: "LLFFFLFLFL", Expected: "2 3 S"},
+		},
+		ExpectedScents: &[]output.ScentRecord{{X: 3, Y: 3, Direction: "N"}},
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	t.Run("Passes when every expectation holds", func(t *testing.T) {
+		report, err := RunScenario(classicScenario())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !report.Passed() {
+			t.Errorf("Expected the scenario to pass, got %+v", report.Mismatches)
+		}
+		if len(report.Results) != 3 {
+			t.Errorf("Expected 3 results, got %d", len(report.Results))
+		}
+	})
+
+	t.Run("Expectations ignore case and spacing", func(t *testing.T) {
+		scenario := classicScenario()
+		scenario.Rovers[1].Expected = "3  3 n lost"
+		scenario.Rovers[2].Instructions = "ll fff lflfl"
+		if report, err := RunScenario(scenario); err != nil || !report.Passed() {
+			t.Errorf("Expected the scenario to pass, got %v (%v)", report, err)
+		}
+	})
+
+	t.Run("Reports every mismatch", func(t *testing.T) {
+		scenario := classicScenario()
+		scenario.Rovers[1].Expected = "3 3 N"
+		scenario.ExpectedScents = &[]output.ScentRecord{{X: 0, Y: 3, Direction: "W"}}
+
+		report, err := RunScenario(scenario)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(report.Mismatches) != 2 {
+			t.Fatalf("Expected 2 mismatches, got %+v", report.Mismatches)
+		}
+		rover := report.Mismatches[0]
+		if rover.Subject != "rover #2" || rover.Expected[0] != "3 3 N" || rover.Got[0] != "3 3 N LOST" {
+			t.Errorf("Expected rover #2 to differ, got %+v", rover)
+		}
+		scents := report.Mismatches[1]
+		if scents.Subject != "scents" || strings.Join(scents.Expected, ",") != "0 3 W" || strings.Join(scents.Got, ",") != "3 3 N" {
+			t.Errorf("Expected the scents to differ, got %+v", scents)
+		}
+	})
+
+	t.Run("Starts from the given obstacles and scents", func(t *testing.T) {
+		scenario := output.ScenarioRecord{
+			Schema: output.ScenarioSchemaVersion, MaxX: 5, MaxY: 3, ObstaclePolicy: "stop",
+			Obstacles: []output.PointRecord{{X: 1, Y: 3}},
+			Scents:    []output.ScentRecord{{X: 3, Y: 3, Direction: "N"}},
+			Rovers: []output.ScenarioRoverRecord{
+				{Pose: "3 2 N", Instructions: "FF", Expected: "3 3 N"},
+				{Pose: "0 3 E", Instructions: "F", Expected: "0 3 E BLOCKED (1,3)"},
+				{Pose: "4 3 N", Expected: "4 3 N"},
+			},
+		}
+		report, err := RunScenario(scenario)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !report.Passed() {
+			t.Errorf("Expected the scenario to pass, got %+v", report.Mismatches)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		scenario func(scenario *output.ScenarioRecord)
+		errMsg   string
+	}{
+		{"Grid too small", func(s *output.ScenarioRecord) { s.MaxX = 0 }, "grid must be between 1,1"},
+		{"Invalid policy", func(s *output.ScenarioRecord) { s.ObstaclePolicy = "jump" }, "invalid obstacle_policy 'jump'"},
+		{"Scent off the grid", func(s *output.ScenarioRecord) { s.Scents = []output.ScentRecord{{X: 9, Y: 9, Direction: "N"}} }, "outside the grid"},
+		{"Invalid pose", func(s *output.ScenarioRecord) { s.Rovers[1].Pose = "3 2" }, "rover #2: "},
+		{"Invalid instructions", func(s *output.ScenarioRecord) { s.Rovers[2].Instructions = "FX" }, "rover #3: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := classicScenario()
+			tt.scenario(&scenario)
+			_, err := RunScenario(scenario)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
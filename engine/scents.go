@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"marster-bot/mars"
+	"marster-bot/output"
+	"sort"
+)
+
+// ScentMap
+// Describes the grid's size and its scents, with the heading each was left from, sorted so saved files are stable.
+func ScentMap(grid *mars.Grid) output.ScentMapRecord {
+	scents := grid.ExitScents()
+	sort.Slice(scents, func(i, j int) bool {
+		a, b := scents[i], scents[j]
+		if a.Position.Y != b.Position.Y {
+			return a.Position.Y < b.Position.Y
+		}
+		if a.Position.X != b.Position.X {
+			return a.Position.X < b.Position.X
+		}
+		return a.Direction.String() < b.Direction.String()
+	})
+
+	scentMap := output.ScentMapRecord{
+		MaxX:   int(grid.XSize),
+		MaxY:   int(grid.YSize),
+		Scents: make([]output.ScentRecord, 0, len(scents)),
+	}
+	for _, scent := range scents {
+		record := output.ScentRecord{
+			X:         int(scent.Position.X),
+			Y:         int(scent.Position.Y),
+			Direction: scent.Direction.String(),
+		}
+		if scent.Instruction != nil {
+			record.Instruction = mars.InstructionCode(scent.Instruction)
+		}
+		scentMap.Scents = append(scentMap.Scents, record)
+	}
+	return scentMap
+}
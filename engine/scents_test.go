@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"bytes"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"strings"
+	"testing"
+)
+
+func TestScentMap(t *testing.T) {
+	t.Run("Round-trips scents with their headings", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+		sim.AddRover(mars.NewRover(3, 2, mars.North, sim.Grid), []mars.Instruction{forward(), forward()})
+		sim.AddRover(mars.NewRover(0, 1, mars.East, sim.Grid), []mars.Instruction{mars.NewMovementInstruction(-2)})
+		sim.Run()
+
+		var buffer bytes.Buffer
+		if err := output.WriteScentMap(&buffer, ScentMap(sim.Grid)); err != nil {
+			t.Fatalf("Failed to write scent map: %v", err)
+		}
+		if !strings.Contains(buffer.String(), `"max_x": 5`) || !strings.Contains(buffer.String(), `"max_y": 3`) {
+			t.Errorf("Expected the grid's upper-right coordinates, got %s", buffer.String())
+		}
+		scentMap, err := output.ReadScentMap(&buffer)
+		if err != nil {
+			t.Fatalf("Failed to read scent map: %v", err)
+		}
+
+		grid := mars.NewGrid(5, 3)
+		if err := input.LoadScentMap(grid, scentMap); err != nil {
+			t.Fatalf("Failed to load scent map: %v", err)
+		}
+
+		expected := []struct {
+			position    mars.Position
+			direction   mars.Direction
+			instruction string
+		}{
+			{mars.NewPosition(0, 1), mars.West, "B2"},
+			{mars.NewPosition(3, 3), mars.North, "F"},
+		}
+		scents := grid.ExitScents()
+		if len(scents) != len(expected) {
+			t.Fatalf("Expected %d scents, got %v", len(expected), scents)
+		}
+		for i, want := range expected {
+			scent := scents[i]
+			if scent.Position != want.position || scent.Direction != want.direction ||
+				scent.Instruction == nil || mars.InstructionCode(scent.Instruction) != want.instruction {
+				t.Errorf("Expected scent %d at %v heading %s from %s, got %+v", i, want.position, want.direction, want.instruction, scent)
+			}
+		}
+	})
+
+	t.Run("Rejects other schemas", func(t *testing.T) {
+		_, err := output.ReadScentMap(strings.NewReader(`{"schema":"marster-bot/v1","max_x":5,"max_y":3}`))
+		if err == nil || !strings.Contains(err.Error(), "unsupported scent map schema") {
+			t.Errorf("Expected schema error, got %v", err)
+		}
+	})
+}
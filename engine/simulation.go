@@ -0,0 +1,170 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"marster-bot/mars"
+)
+
+// RoverResult
+// The outcome of running a single rover's instructions against the simulation grid.
+type RoverResult struct {
+	Number         int
+	StartPosition  mars.Position
+	StartDirection mars.Direction
+	Instructions   []mars.Instruction
+	Position       mars.Position
+	Direction      mars.Direction
+	Lost           bool
+	ScentAdded     *mars.Position
+	Obstacle       *mars.Position
+	Collision      *mars.CollisionError
+	Loop           *mars.LoopLimitError
+	Collisions     []mars.Collision
+	Trail          []mars.Position
+	Err            error
+}
+
+func (r *RoverResult) String() string {
+	pose := fmt.Sprintf("%d %d %s", r.Position.X, r.Position.Y, r.Direction)
+	if r.Lost {
+		return pose + " LOST"
+	}
+	if r.Obstacle != nil {
+		return pose + " BLOCKED " + r.Obstacle.String()
+	}
+	if r.Collision != nil {
+		return fmt.Sprintf("%s COLLIDED #%d %s", pose, r.Collision.Other, r.Collision.Position.String())
+	}
+	if r.Loop != nil {
+		return pose + " LOOP LIMIT until " + r.Loop.Until.String()
+	}
+	return pose
+}
+
+type pendingRover struct {
+	number       int
+	rover        *mars.Rover
+	instructions []mars.Instruction
+}
+
+// Simulation
+// Owns a grid and runs rovers against it in the order they were added, so scents left by one rover are
+// visible to every rover after it.
+type Simulation struct {
+	Grid    *mars.Grid
+	pending []pendingRover
+	results []*RoverResult
+	count   int
+}
+
+func NewSimulation(grid *mars.Grid) *Simulation {
+	return &Simulation{
+		Grid: grid,
+	}
+}
+
+// AddRover
+// Queues a rover and its instructions for the next call to Run, returning the rover's number in this simulation.
+func (s *Simulation) AddRover(rover *mars.Rover, instructions []mars.Instruction) int {
+	s.count++
+	rover.ID = s.count
+	s.pending = append(s.pending, pendingRover{
+		number:       s.count,
+		rover:        rover,
+		instructions: instructions,
+	})
+	return s.count
+}
+
+// Run
+// Executes every queued rover in order and returns their results.
+func (s *Simulation) Run() []*RoverResult {
+	results := make([]*RoverResult, 0, len(s.pending))
+	for _, p := range s.pending {
+		results = append(results, s.runRover(s.Grid, p))
+	}
+	s.pending = nil
+	s.results = append(s.results, results...)
+	return results
+}
+
+// Pending
+// Returns the number of rovers queued for the next call to Run.
+func (s *Simulation) Pending() int {
+	return len(s.pending)
+}
+
+// Results
+// Returns the results of every rover run so far.
+func (s *Simulation) Results() []*RoverResult {
+	return s.results
+}
+
+// runRover
+// Runs a queued rover's instructions against the given grid, which is the simulation's grid unless the rover is
+// running concurrently.
+func (s *Simulation) runRover(grid *mars.Grid, p pendingRover) *RoverResult {
+	rover := p.rover
+	result := &RoverResult{
+		Number:         p.number,
+		StartPosition:  rover.Position.Copy(),
+		StartDirection: rover.Direction,
+		Instructions:   p.instructions,
+	}
+
+	if err := place(grid, rover); err != nil {
+		result.Collision = err
+		result.Err = err
+		result.Position = rover.Position.Copy()
+		result.Direction = rover.Direction
+		return result
+	}
+
+	for _, instruction := range p.instructions {
+		err := rover.Instruct(instruction)
+		if err != nil {
+			var obstacleErr *mars.ObstacleError
+			if errors.As(err, &obstacleErr) {
+				result.Obstacle = &obstacleErr.Position
+			}
+			var collisionErr *mars.CollisionError
+			if errors.As(err, &collisionErr) {
+				result.Collision = collisionErr
+			}
+			var loopErr *mars.LoopLimitError
+			if errors.As(err, &loopErr) {
+				result.Loop = loopErr
+			}
+			result.Err = err
+			break
+		}
+	}
+
+	result.Position = rover.Position.Copy()
+	result.Direction = rover.Direction
+	result.Lost = rover.Lost
+	result.Collisions = rover.Collisions
+	result.Trail = rover.Trail
+	if rover.Lost {
+		result.ScentAdded = &result.Position
+	}
+
+	return result
+}
+
+// place
+// Puts the rover on the grid at its starting position, applying the grid's collision policy if another rover
+// is already there.
+func place(grid *mars.Grid, rover *mars.Rover) *mars.CollisionError {
+	if other := grid.OccupantAt(rover.Position, rover); other != nil {
+		collision := mars.Collision{Position: rover.Position.Copy(), Other: other.ID}
+		if grid.CollisionPolicy != mars.CollisionAllow {
+			return &mars.CollisionError{Position: collision.Position, Rover: rover.ID, Other: other.ID}
+		}
+		rover.Collisions = append(rover.Collisions, collision)
+	}
+
+	grid.Occupy(rover)
+	return nil
+}
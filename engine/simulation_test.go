@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"marster-bot/mars"
+	"testing"
+)
+
+func newTestSimulation(xSize, ySize uint8) *Simulation {
+	return NewSimulation(mars.NewGrid(xSize, ySize))
+}
+
+func forward() mars.Instruction {
+	return mars.NewMovementInstruction(1)
+}
+
+func right() mars.Instruction {
+	return mars.NewOrientationInstruction(mars.Right)
+}
+
+func left() mars.Instruction {
+	return mars.NewOrientationInstruction(mars.Left)
+}
+
+func TestSimulationRun(t *testing.T) {
+	t.Run("Runs queued rovers in order and reports results", func(t *testing.T) {
+		sim := newTestSimulation(5, 3)
+
+		// 1 1 E RFRFRFRF -> 1 1 E
+		sim.AddRover(mars.NewRover(1, 1, mars.East, sim.Grid), []mars.Instruction{
+			right(), forward(), right(), forward(), right(), forward(), right(), forward(),
+		})
+		// 3 2 N FRRFLLFFRRFLL -> 3 3 N LOST
+		sim.AddRover(mars.NewRover(3, 2, mars.North, sim.Grid), []mars.Instruction{
+			forward(), right(), right(), forward(), left(), left(), forward(), forward(),
+			right(), right(), forward(), left(), left(),
+		})
+		// 0 3 W LLFFFLFLFL -> 2 3 S
+		sim.AddRover(mars.NewRover(0, 3, mars.West, sim.Grid), []mars.Instruction{
+			left(), left(), forward(), forward(), forward(), left(), forward(), left(), forward(), left(),
+		})
+
+		results := sim.Run()
+
+		expected := []string{"1 1 E", "3 3 N LOST", "2 3 S"}
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		}
+		for i, want := range expected {
+			if results[i].Number != i+1 {
+				t.Errorf("Expected rover number %d, got %d", i+1, results[i].Number)
+			}
+			if results[i].String() != want {
+				t.Errorf("Rover #%d: expected %q, got %q", i+1, want, results[i].String())
+			}
+		}
+	})
+
+	t.Run("Lost rover records its error and start pose", func(t *testing.T) {
+		sim := newTestSimulation(3, 3)
+		sim.AddRover(mars.NewRover(1, 3, mars.North, sim.Grid), []mars.Instruction{forward(), right()})
+
+		result := sim.Run()[0]
+
+		if !result.Lost {
+			t.Error("Expected rover to be lost")
+		}
+		if result.Err == nil {
+			t.Error("Expected an error for the lost rover")
+		}
+		if !result.StartPosition.Equals(mars.NewPosition(1, 3)) || !result.StartDirection.Equals(mars.North) {
+			t.Errorf("Expected start pose 1 3 N, got %d %d %s",
+				result.StartPosition.X, result.StartPosition.Y, result.StartDirection)
+		}
+		if !result.Direction.Equals(mars.North) {
+			t.Errorf("Expected instructions after the fall to be skipped, got direction %s", result.Direction)
+		}
+	})
+
+	t.Run("Results accumulate across runs", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.AddRover(mars.NewRover(0, 0, mars.North, sim.Grid), []mars.Instruction{forward()})
+		sim.Run()
+		number := sim.AddRover(mars.NewRover(1, 1, mars.East, sim.Grid), []mars.Instruction{forward()})
+		sim.Run()
+
+		if number != 2 {
+			t.Errorf("Expected second rover to be numbered 2, got %d", number)
+		}
+		if len(sim.Results()) != 2 {
+			t.Errorf("Expected 2 accumulated results, got %d", len(sim.Results()))
+		}
+	})
+
+	t.Run("Obstacle stop is reported on the result", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.Grid.ObstaclePolicy = mars.ObstacleStop
+		sim.Grid.AddObstacle(mars.NewPosition(1, 3))
+		sim.AddRover(mars.NewRover(1, 0, mars.North, sim.Grid), []mars.Instruction{forward(), forward(), forward(), right()})
+
+		result := sim.Run()[0]
+
+		if result.Obstacle == nil || !result.Obstacle.Equals(mars.NewPosition(1, 3)) {
+			t.Fatalf("Expected obstacle at (1,3), got %v", result.Obstacle)
+		}
+		if result.Lost {
+			t.Error("Expected a blocked rover not to be lost")
+		}
+		if result.String() != "1 2 N BLOCKED (1,3)" {
+			t.Errorf("Expected \"1 2 N BLOCKED (1,3)\", got %q", result.String())
+		}
+	})
+
+	t.Run("Finished rovers block later rovers", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		sim.Grid.CollisionPolicy = mars.CollisionAbort
+		sim.AddRover(mars.NewRover(2, 2, mars.North, sim.Grid), []mars.Instruction{forward()})
+		sim.AddRover(mars.NewRover(2, 0, mars.North, sim.Grid), []mars.Instruction{forward(), forward(), forward()})
+		sim.AddRover(mars.NewRover(2, 3, mars.South, sim.Grid), []mars.Instruction{forward()})
+
+		results := sim.Run()
+
+		if results[1].Collision == nil || results[1].Collision.Rover != 2 || results[1].Collision.Other != 1 {
+			t.Fatalf("Expected rover #2 to collide with rover #1, got %v", results[1].Err)
+		}
+		if results[1].String() != "2 2 N COLLIDED #1 (2,3)" {
+			t.Errorf("Expected \"2 2 N COLLIDED #1 (2,3)\", got %q", results[1].String())
+		}
+		if results[2].Collision == nil || results[2].Collision.Other != 1 {
+			t.Errorf("Expected rover #3 to be refused its occupied start cell, got %v", results[2].Err)
+		}
+	})
+
+	t.Run("Loop limit is reported on the result", func(t *testing.T) {
+		sim := newTestSimulation(5, 5)
+		spin := &mars.LoopInstruction{Until: mars.ConditionOffGrid, Body: []mars.Instruction{right()}}
+		sim.AddRover(mars.NewRover(2, 2, mars.North, sim.Grid), []mars.Instruction{spin, forward()})
+
+		results := sim.Run()
+
+		if results[0].Loop == nil || results[0].Err == nil {
+			t.Fatalf("Expected a loop limit, got %v", results[0].Err)
+		}
+		if results[0].String() != "2 2 N LOOP LIMIT until offgrid" {
+			t.Errorf("Expected \"2 2 N LOOP LIMIT until offgrid\", got %q", results[0].String())
+		}
+		if record := results[0].Record(); record.ErrorKind == nil || *record.ErrorKind != "loop_limit" {
+			t.Errorf("Expected error kind loop_limit, got %v", record.ErrorKind)
+		}
+	})
+}
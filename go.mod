@@ -3,9 +3,12 @@ module marster-bot
 go 1.25
 
 require (
-	github.com/fatih/color v1.18.0 // indirect
+	github.com/fatih/color v1.18.0
+	github.com/urfave/cli/v3 v3.4.1
+	golang.org/x/sys v0.36.0
+)
+
+require (
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/urfave/cli/v3 v3.4.1 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 )
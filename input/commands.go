@@ -0,0 +1,97 @@
+package input
+
+import (
+	"fmt"
+	"marster-bot/output"
+	"strings"
+)
+
+// CommandPrefix
+// Marks a line typed at any prompt as a meta-command rather than data, e.g. ':map'.
+const CommandPrefix = ":"
+
+// Command
+// A meta-command the operator can type at any prompt. Args describes its arguments for ':help'.
+type Command struct {
+	Name  string
+	Args  string
+	Usage string
+	Run   func(args []string) error
+}
+
+// Dispatcher
+// Recognises meta-commands in prompt input and runs them, reporting errors on the console. ':help' is always
+// registered and lists every command in the order they were registered.
+type Dispatcher struct {
+	console  *output.Console
+	commands []Command
+}
+
+func NewDispatcher(console *output.Console) *Dispatcher {
+	d := &Dispatcher{console: console}
+	d.Register(Command{Name: "help", Usage: "List the available commands", Run: d.help})
+	return d
+}
+
+// Register
+// Adds commands to the dispatcher, replacing any already registered under the same name.
+func (d *Dispatcher) Register(commands ...Command) {
+	for _, command := range commands {
+		if i := d.find(command.Name); i >= 0 {
+			d.commands[i] = command
+			continue
+		}
+		d.commands = append(d.commands, command)
+	}
+}
+
+// Handle
+// Runs the line as a meta-command if it starts with CommandPrefix and reports whether it did. Unknown commands and
+// command errors are reported on the console and still count as handled, so they never reach the data prompts.
+func (d *Dispatcher) Handle(line string) bool {
+	if !strings.HasPrefix(line, CommandPrefix) {
+		return false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, CommandPrefix))
+	if len(fields) == 0 {
+		d.console.Error("expected a command after '%s', type %shelp to list them", CommandPrefix, CommandPrefix)
+		return true
+	}
+
+	name := strings.ToLower(fields[0])
+	i := d.find(name)
+	if i < 0 {
+		d.console.Error("unknown command '%s%s', type %shelp to list them", CommandPrefix, name, CommandPrefix)
+		return true
+	}
+
+	if err := d.commands[i].Run(fields[1:]); err != nil {
+		d.console.Error("%s%s: %v", CommandPrefix, name, err)
+	}
+	return true
+}
+
+func (d *Dispatcher) find(name string) int {
+	for i, command := range d.commands {
+		if command.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Dispatcher) help(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected no arguments")
+	}
+
+	for _, command := range d.commands {
+		usage := CommandPrefix + command.Name
+		if command.Args != "" {
+			usage += " " + command.Args
+		}
+		d.console.Info("  %-22s %s", usage, command.Usage)
+	}
+	return nil
+}
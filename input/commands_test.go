@@ -0,0 +1,89 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"marster-bot/output"
+	"strings"
+	"testing"
+)
+
+func TestDispatcher(t *testing.T) {
+	var ran []string
+	newDispatcher := func(out *bytes.Buffer) *Dispatcher {
+		console := output.NewConsole(*bufio.NewReader(strings.NewReader("")), false)
+		console.SetWriter(out)
+		dispatcher := NewDispatcher(console)
+		dispatcher.Register(
+			Command{Name: "scents", Usage: "List scents", Run: func(args []string) error {
+				ran = append(ran, "scents "+strings.Join(args, " "))
+				return nil
+			}},
+			Command{Name: "save", Args: "<file>", Usage: "Save the session", Run: func(args []string) error {
+				return errors.New("expected a file name")
+			}},
+		)
+		return dispatcher
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		handled bool
+		ran     string
+		output  string
+	}{
+		{name: "Data is passed through", line: "1 2 N", handled: false},
+		{name: "Runs a command with its arguments", line: ":scents a b", handled: true, ran: "scents a b"},
+		{name: "Command names are case-insensitive", line: ":SCENTS", handled: true, ran: "scents "},
+		{name: "Reports command errors", line: ":save", handled: true, output: ":save: expected a file name"},
+		{name: "Reports unknown commands", line: ":fly", handled: true, output: "unknown command ':fly'"},
+		{name: "Reports a missing command", line: ":", handled: true, output: "expected a command after ':'"},
+		{name: "Lists commands in order", line: ":help", handled: true, output: ":help"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran = nil
+			var out bytes.Buffer
+			handled := newDispatcher(&out).Handle(tt.line)
+
+			if handled != tt.handled {
+				t.Errorf("Expected handled to be %v, got %v", tt.handled, handled)
+			}
+			if tt.ran != "" && (len(ran) != 1 || ran[0] != tt.ran) {
+				t.Errorf("Expected %q to run, got %q", tt.ran, ran)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("Expected output containing %q, got %q", tt.output, out.String())
+			}
+		})
+	}
+
+	t.Run("Help lists every command with its arguments", func(t *testing.T) {
+		var out bytes.Buffer
+		newDispatcher(&out).Handle(":help")
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 || !strings.Contains(lines[2], ":save <file>") {
+			t.Errorf("Expected help, scents and save in order, got %q", lines)
+		}
+	})
+
+	t.Run("Prompts skip handled commands", func(t *testing.T) {
+		var out bytes.Buffer
+		console := output.NewConsole(*bufio.NewReader(strings.NewReader(":help\n:scents\n5,5\n")), false)
+		console.SetWriter(&out)
+		dispatcher := NewDispatcher(console)
+		console.SetCommandHandler(dispatcher.Handle)
+
+		grid, err := CollectGridFromInput(console)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if grid.XSize != 5 || !strings.Contains(out.String(), "unknown command ':scents'") {
+			t.Errorf("Expected the grid after the commands, got %dx%d and %q", grid.XSize, grid.YSize, out.String())
+		}
+	})
+}
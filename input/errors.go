@@ -0,0 +1,50 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrExit
+// Returned by the prompts when the operator asks to quit.
+var ErrExit = errors.New("exit")
+
+// Fields reported by ParseError.
+const (
+	FieldGrid         = "grid"
+	FieldX            = "x"
+	FieldY            = "y"
+	FieldDirection    = "direction"
+	FieldPosition     = "position"
+	FieldObstacle     = "obstacle"
+	FieldEdge         = "edge"
+	FieldInstructions = "instructions"
+	FieldDistance     = "distance"
+)
+
+// ParseError
+// Describes invalid input: which field was wrong, the 0-based column in Input where the problem starts, and the raw
+// input itself.
+type ParseError struct {
+	Field  string
+	Column int
+	Input  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func newParseError(field string, input string, column int, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Field:  field,
+		Column: column,
+		Input:  input,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
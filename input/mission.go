@@ -0,0 +1,162 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"marster-bot/mars"
+	"strings"
+	"unicode"
+)
+
+// MissionRover
+// A rover parsed from a mission file, along with the line its pose was declared on.
+type MissionRover struct {
+	Rover        *mars.Rover
+	Instructions []mars.Instruction
+	Line         int
+}
+
+// Mission
+// A grid and the rovers to run against it, in the order they appear in the mission file named File.
+type Mission struct {
+	File   string
+	Grid   *mars.Grid
+	Rovers []MissionRover
+}
+
+// MissionError
+// A parse error annotated with the mission file name and the line it occurred on.
+type MissionError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *MissionError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *MissionError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	obstacleKeyword = "obstacle"
+	edgeKeyword     = "edge"
+	defKeyword      = "def"
+)
+
+func isKeywordLine(line, keyword string) bool {
+	return len(line) > len(keyword) && strings.EqualFold(line[:len(keyword)], keyword)
+}
+
+// ParseMission
+// Parses the classic multi-rover input format: a grid line ('5 3') followed by pairs of rover pose ('1 1 E')
+// and instruction ('RFRFRFRF') lines. Obstacles may be declared between rovers with 'obstacle x y' lines, the
+// grid's edge policy with an 'edge wrap' line before the first rover, and blank lines are ignored. Macros defined on
+// 'def NAME = ...' lines can be called from the instruction lines that follow them.
+func ParseMission(reader io.Reader, name string) (*Mission, error) {
+	scanner := bufio.NewScanner(reader)
+	mission := &Mission{File: name}
+	macros := Macros{}
+	lineNum := 0
+
+	var pending *mars.Rover
+	pendingLine := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		missionErr := func(err error) error {
+			return &MissionError{File: name, Line: lineNum, Err: err}
+		}
+
+		switch {
+		case mission.Grid == nil:
+			parts, columns := fieldsWithColumns(line)
+			if len(parts) != 2 {
+				return nil, missionErr(newParseError(FieldGrid, line, 0,
+					"expected grid format 'x y' (e.g., '5 3'), got '%s'", line))
+			}
+			grid, err := parseGridSize(line, parts, columns)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+			mission.Grid = grid
+		case pending == nil && isKeywordLine(line, edgeKeyword):
+			if len(mission.Rovers) > 0 {
+				return nil, missionErr(fmt.Errorf("edge must be declared before the first rover"))
+			}
+			err := ParseEdge(strings.TrimSpace(line[len(edgeKeyword):]), mission.Grid)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+		case mission.Grid != nil && isKeywordLine(line, defKeyword) && unicode.IsSpace(rune(line[len(defKeyword)])):
+			instructions, err := ParseProgram(line, macros)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+			if len(instructions) > 0 {
+				return nil, missionErr(fmt.Errorf("a def line can only define macros, give instructions after a rover line"))
+			}
+		case pending == nil && isKeywordLine(line, obstacleKeyword):
+			err := ParseObstacle(strings.TrimSpace(line[len(obstacleKeyword):]), mission.Grid)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+		case pending == nil:
+			rover, err := ParseRover(line, mission.Grid)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+			pending = rover
+			pendingLine = lineNum
+		default:
+			instructions, err := ParseProgram(line, macros)
+			if err != nil {
+				return nil, missionErr(err)
+			}
+			if len(instructions) == 0 {
+				return nil, missionErr(fmt.Errorf("rover has no instructions: the line only defines macros"))
+			}
+			mission.Rovers = append(mission.Rovers, MissionRover{
+				Rover:        pending,
+				Instructions: instructions,
+				Line:         pendingLine,
+			})
+			pending = nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if mission.Grid == nil {
+		return nil, &MissionError{File: name, Line: lineNum, Err: fmt.Errorf("mission is empty: expected a grid line")}
+	}
+
+	if pending != nil {
+		return nil, &MissionError{File: name, Line: pendingLine, Err: fmt.Errorf("rover has no instruction line")}
+	}
+
+	return mission, nil
+}
+
+// CheckStarts
+// Checks that no rover starts on an obstacle, for obstacles added to the grid after the mission was parsed, e.g.
+// from the command line. Obstacles declared in the mission file are already checked by ParseMission.
+func (m *Mission) CheckStarts() error {
+	for _, rover := range m.Rovers {
+		if position := rover.Rover.Position; m.Grid.IsObstacle(position) {
+			return &MissionError{File: m.File, Line: rover.Line,
+				Err: fmt.Errorf("position (%d,%d) is blocked by an obstacle", position.X, position.Y)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package input
+
+import (
+	"errors"
+	"marster-bot/mars"
+	"strings"
+	"testing"
+)
+
+func TestParseMission(t *testing.T) {
+	t.Run("Parses grid and rover pairs", func(t *testing.T) {
+		text := "5 3\n1 1 E\nRFRFRFRF\n\n3 2 N\nFRRFLLFFRRFLL\n"
+
+		mission, err := ParseMission(strings.NewReader(text), "missions.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if mission.Grid.XSize != 5 || mission.Grid.YSize != 3 {
+			t.Errorf("Expected grid size (5,3), got (%d,%d)", mission.Grid.XSize, mission.Grid.YSize)
+		}
+		if len(mission.Rovers) != 2 {
+			t.Fatalf("Expected 2 rovers, got %d", len(mission.Rovers))
+		}
+
+		second := mission.Rovers[1]
+		if second.Line != 5 {
+			t.Errorf("Expected second rover on line 5, got %d", second.Line)
+		}
+		if !second.Rover.Position.Equals(mars.NewPosition(3, 2)) || !second.Rover.Direction.Equals(mars.North) {
+			t.Errorf("Expected rover at 3 2 N, got %d %d %s",
+				second.Rover.Position.X, second.Rover.Position.Y, second.Rover.Direction)
+		}
+		if len(second.Instructions) != 13 {
+			t.Errorf("Expected 13 instructions, got %d", len(second.Instructions))
+		}
+		if second.Rover.Grid != mission.Grid {
+			t.Error("Expected every rover to share the mission grid")
+		}
+	})
+
+	t.Run("Parses obstacle lines", func(t *testing.T) {
+		text := "5 3\nobstacle 2 1\n1 1 E\nF\nOBSTACLE 4,3\n"
+
+		mission, err := ParseMission(strings.NewReader(text), "missions.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !mission.Grid.IsObstacle(mars.NewPosition(2, 1)) || !mission.Grid.IsObstacle(mars.NewPosition(4, 3)) {
+			t.Errorf("Expected obstacles at (2,1) and (4,3), got %v", mission.Grid.Obstacles())
+		}
+		if len(mission.Rovers) != 1 {
+			t.Errorf("Expected 1 rover, got %d", len(mission.Rovers))
+		}
+	})
+
+	t.Run("Calls macros defined on def lines", func(t *testing.T) {
+		text := "5 3\ndef sweep = 2(FR)\n1 1 E\n@sweep\n3 2 N\n2(@sweep)\n"
+
+		mission, err := ParseMission(strings.NewReader(text), "missions.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(mission.Rovers) != 2 {
+			t.Fatalf("Expected 2 rovers, got %d", len(mission.Rovers))
+		}
+		if len(mission.Rovers[0].Instructions) != 4 || len(mission.Rovers[1].Instructions) != 8 {
+			t.Errorf("Expected 4 and 8 instructions, got %d and %d",
+				len(mission.Rovers[0].Instructions), len(mission.Rovers[1].Instructions))
+		}
+	})
+
+	t.Run("Rechecks starts against obstacles added later", func(t *testing.T) {
+		mission, err := ParseMission(strings.NewReader("5 3\n1 1 E\nF\n2 2 N\nF\n"), "missions.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := mission.CheckStarts(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		mission.Grid.AddObstacle(mars.NewPosition(2, 2))
+		err = mission.CheckStarts()
+		var missionErr *MissionError
+		if !errors.As(err, &missionErr) || missionErr.Line != 4 {
+			t.Fatalf("Expected an error for the rover on line 4, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "position (2,2) is blocked by an obstacle") {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Parses an edge line", func(t *testing.T) {
+		mission, err := ParseMission(strings.NewReader("5 3\nEdge wrap\n1 1 E\nF\n"), "missions.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if mission.Grid.EdgePolicy != mars.EdgeWrap {
+			t.Errorf("Expected wrap edges, got %s", mission.Grid.EdgePolicy)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		input  string
+		line   int
+		errMsg string
+	}{
+		{
+			name:   "Empty mission",
+			input:  "\n\n",
+			line:   2,
+			errMsg: "mission is empty",
+		},
+		{
+			name:   "Invalid grid line",
+			input:  "5,3\n",
+			line:   1,
+			errMsg: "expected grid format 'x y'",
+		},
+		{
+			name:   "Invalid rover direction",
+			input:  "5 3\n1 1 E\nF\n2 2 Q\nF\n",
+			line:   4,
+			errMsg: "invalid direction 'Q'",
+		},
+		{
+			name:   "Invalid instruction",
+			input:  "5 3\n\n1 1 E\nFFX\n",
+			line:   4,
+			errMsg: "invalid instruction 'X' at position 2",
+		},
+		{
+			name:   "Obstacle outside grid",
+			input:  "5 3\nobstacle 6 1\n",
+			line:   2,
+			errMsg: "obstacle (6,1) is outside grid bounds",
+		},
+		{
+			name:   "Rover starting on an obstacle",
+			input:  "5 3\nobstacle 1 1\n1 1 E\nF\n",
+			line:   3,
+			errMsg: "position (1,1) is blocked by an obstacle",
+		},
+		{
+			name:   "Unknown edge policy",
+			input:  "5 3\nedge sticky\n",
+			line:   2,
+			errMsg: "invalid edge 'sticky'",
+		},
+		{
+			name:   "Edge after the first rover",
+			input:  "5 3\n1 1 E\nF\nedge clamp\n",
+			line:   4,
+			errMsg: "edge must be declared before the first rover",
+		},
+		{
+			name:   "Rover without instructions",
+			input:  "5 3\n1 1 E\nF\n2 2 N\n\n",
+			line:   4,
+			errMsg: "rover has no instruction line",
+		},
+		{
+			name:   "Macro called before it is defined",
+			input:  "5 3\n1 1 E\n@sweep\ndef sweep = F\n",
+			line:   3,
+			errMsg: "undefined macro '@sweep'",
+		},
+		{
+			name:   "Def line with instructions",
+			input:  "5 3\ndef sweep = F; @sweep\n1 1 E\nF\n",
+			line:   2,
+			errMsg: "a def line can only define macros",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMission(strings.NewReader(tt.input), "missions.txt")
+			if err == nil {
+				t.Fatalf("Expected error containing '%s', got nil", tt.errMsg)
+			}
+
+			var missionErr *MissionError
+			if !errors.As(err, &missionErr) {
+				t.Fatalf("Expected a MissionError, got %T", err)
+			}
+			if missionErr.File != "missions.txt" || missionErr.Line != tt.line {
+				t.Errorf("Expected error at missions.txt:%d, got %s:%d", tt.line, missionErr.File, missionErr.Line)
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
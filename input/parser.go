@@ -6,6 +6,7 @@ import (
 	"marster-bot/output"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -18,119 +19,301 @@ func CollectGridFromInput(console *output.Console) (*mars.Grid, error) {
 	parts := strings.Split(gridInput, ",")
 
 	if len(parts) != 2 {
-		return nil, fmt.Errorf("expected format 'x,y' (e.g., '5,5'), got '%s'", gridInput)
+		return nil, newParseError(FieldGrid, gridInput, 0, "expected format 'x,y' (e.g., '5,5'), got '%s'", gridInput)
 	}
 
-	maxX, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	grid, err := parseGridSize(gridInput, parts, []int{0, len(parts[0]) + 1})
 	if err != nil {
-		return nil, fmt.Errorf("invalid x boundary: '%s' is not a number", parts[0])
+		return nil, err
 	}
 
-	maxY, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	console.Success("Grid established: %dx%d", grid.XSize, grid.YSize)
+
+	return grid, nil
+}
+
+func CollectRoverFromInput(console *output.Console, grid *mars.Grid) (*mars.Rover, error) {
+	positionInput, err := console.Prompt("Enter rover position and direction (x y D), ':help' for commands, or 'exit' to quit: ")
 	if err != nil {
-		return nil, fmt.Errorf("invalid y boundary: '%s' is not a number", parts[1])
+		console.Error("Failed to read rover position: %v", err)
+		return nil, err
 	}
 
-	if maxX < 0 || maxY < 0 {
-		return nil, fmt.Errorf("grid boundaries must be positive (got %d,%d)", maxX, maxY)
+	if strings.ToLower(positionInput) == "exit" {
+		return nil, ErrExit
 	}
 
-	if maxX == 0 || maxY == 0 {
-		return nil, fmt.Errorf("grid must have non-zero dimensions (got %d,%d)", maxX, maxY)
+	rover, err := ParseRover(positionInput, grid)
+	if err != nil {
+		return nil, err
 	}
 
-	grid := mars.NewGrid(uint8(maxX), uint8(maxY))
+	console.Success("Rover positioned at (%d, %d) facing %s", rover.Position.X, rover.Position.Y, rover.Direction)
 
-	console.Success("Grid established: %dx%d", grid.XSize, grid.YSize)
+	return rover, nil
+}
 
-	return grid, nil
+func CollectInstructionsFromInput(console *output.Console) (*[]mars.Instruction, error) {
+	return CollectProgramFromInput(console, Macros{})
 }
 
-func CollectRoverFromInput(console *output.Console, grid *mars.Grid) (*mars.Rover, error) {
-	positionInput, err := console.Prompt("Enter rover position and direction (x y D) or 'exit' to quit: ")
-	if err != nil {
-		console.Error("Failed to read rover position: %v", err)
-		return nil, err
+// CollectProgramFromInput
+// Prompts for a rover's instructions in the instruction language, keeping macro definitions in macros for later
+// rovers. A line that only defines macros is acknowledged and the prompt is shown again.
+func CollectProgramFromInput(console *output.Console, macros Macros) (*[]mars.Instruction, error) {
+	for {
+		instructionInput, err := console.Prompt(fmt.Sprintf("Enter movement instructions (%s, e.g. F3, 4(FFR), DEF NAME = ..., @NAME, IF BLOCKED (R) ELSE (F)): ",
+			mars.InstructionHelp()))
+		if err != nil {
+			console.Error("Failed to read instructions: %v", err)
+			return nil, err
+		}
+		instructions, defined, err := parseProgram(instructionInput, macros)
+		if err != nil {
+			return nil, err
+		}
+		instructionInput = strings.TrimSpace(strings.ToUpper(instructionInput))
+
+		for _, name := range defined {
+			console.Success("Macro @%s defined: %d instructions", name, len(macros[name]))
+		}
+		if len(instructions) == 0 {
+			continue
+		}
+
+		for _, instruction := range instructions {
+			console.Debug("New instruction: %v", instruction)
+		}
+
+		console.Success("Instructions received: %s", instructionInput)
+		if program := mars.ProgramCode(instructions); program != instructionInput {
+			console.Info("Expanded to %d instructions: %s", len(instructions), program)
+		}
+
+		return &instructions, nil
 	}
+}
 
-	if strings.ToLower(positionInput) == "exit" {
-		return nil, fmt.Errorf("exit")
+// ParseMapCenter
+// Parses the optional 'x y' cell a map should be centred on, returning nil when none is given.
+func ParseMapCenter(args []string, grid *mars.Grid) (*mars.Position, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected no arguments or 'x y', got '%s'", strings.Join(args, " "))
 	}
 
-	parts := strings.Fields(positionInput)
+	x, errX := strconv.Atoi(args[0])
+	y, errY := strconv.Atoi(args[1])
+	if errX != nil || errY != nil || x < 0 || y < 0 || x > int(grid.XSize) || y > int(grid.YSize) {
+		return nil, fmt.Errorf("map centre must be a cell within (0-%d,0-%d)", grid.XSize, grid.YSize)
+	}
+
+	center := mars.NewPosition(int8(x), int8(y))
+	return &center, nil
+}
+
+// ParseRover
+// Parses a rover pose in the form 'x y D' and places the rover on the grid.
+func ParseRover(positionInput string, grid *mars.Grid) (*mars.Rover, error) {
+	parts, columns := fieldsWithColumns(positionInput)
 
 	if len(parts) != 3 {
-		return nil, fmt.Errorf("expected format 'x y D' where D is N/S/E/W (e.g., '1 2 N'), got '%s'", positionInput)
+		return nil, newParseError(FieldPosition, positionInput, 0,
+			"expected format 'x y D' where D is N/S/E/W (e.g., '1 2 N'), got '%s'", positionInput)
 	}
 
 	x, err := strconv.Atoi(parts[0])
 	if err != nil {
-		return nil, fmt.Errorf("invalid x position: '%s' is not a number", parts[0])
+		return nil, newParseError(FieldX, positionInput, columns[0], "invalid x position: '%s' is not a number", parts[0])
 	}
 
 	y, err := strconv.Atoi(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("invalid y position: '%s' is not a number", parts[1])
+		return nil, newParseError(FieldY, positionInput, columns[1], "invalid y position: '%s' is not a number", parts[1])
 	}
 
 	directionCode, _ := utf8.DecodeRuneInString(parts[2])
 	if directionCode != 'N' && directionCode != 'S' && directionCode != 'E' && directionCode != 'W' {
-		return nil, fmt.Errorf("invalid direction '%s': must be N, S, E, or W", string(directionCode))
+		return nil, newParseError(FieldDirection, positionInput, columns[2],
+			"invalid direction '%s': must be N, S, E, or W", string(directionCode))
+	}
+
+	if x < 0 || x > int(grid.XSize) {
+		return nil, newParseError(FieldX, positionInput, columns[0],
+			"x position %d is outside grid bounds (0-%d)", x, grid.XSize)
 	}
 
-	if x < 0 || uint8(x) > grid.XSize {
-		return nil, fmt.Errorf("x position %d is outside grid bounds (0-%d)", x, grid.XSize)
+	if y < 0 || y > int(grid.YSize) {
+		return nil, newParseError(FieldY, positionInput, columns[1],
+			"y position %d is outside grid bounds (0-%d)", y, grid.YSize)
 	}
 
-	if y < 0 || uint8(y) > grid.YSize {
-		return nil, fmt.Errorf("y position %d is outside grid bounds (0-%d)", y, grid.YSize)
+	if grid.IsObstacle(mars.NewPosition(int8(x), int8(y))) {
+		return nil, newParseError(FieldPosition, positionInput, columns[0],
+			"position (%d,%d) is blocked by an obstacle", x, y)
 	}
 
 	direction := mars.DirectionFromCode(directionCode)
 
-	rover := mars.NewRover(int8(x), int8(y), direction, grid)
+	return mars.NewRover(int8(x), int8(y), direction, grid), nil
+}
 
-	console.Success("Rover positioned at (%d, %d) facing %s", rover.Position.X, rover.Position.Y, rover.Direction)
+// ParseTarget
+// Parses a planner target in the form 'x y', or 'x y D' to also fix the direction the rover should face.
+func ParseTarget(targetInput string, grid *mars.Grid) (mars.Position, *mars.Direction, error) {
+	parts, columns := fieldsWithColumns(targetInput)
 
-	return rover, nil
+	if len(parts) != 2 && len(parts) != 3 {
+		return mars.Position{}, nil, newParseError(FieldPosition, targetInput, 0,
+			"expected target format 'x y' or 'x y D' (e.g., '3 3' or '3 3 N'), got '%s'", targetInput)
+	}
+
+	x, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return mars.Position{}, nil, newParseError(FieldX, targetInput, columns[0],
+			"invalid target x position: '%s' is not a number", parts[0])
+	}
+
+	y, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return mars.Position{}, nil, newParseError(FieldY, targetInput, columns[1],
+			"invalid target y position: '%s' is not a number", parts[1])
+	}
+
+	if x < 0 || y < 0 || x > int(grid.XSize) || y > int(grid.YSize) {
+		return mars.Position{}, nil, newParseError(FieldPosition, targetInput, columns[0],
+			"target (%d,%d) is outside grid bounds (0-%d,0-%d)", x, y, grid.XSize, grid.YSize)
+	}
+
+	position := mars.NewPosition(int8(x), int8(y))
+	if grid.IsObstacle(position) {
+		return mars.Position{}, nil, newParseError(FieldPosition, targetInput, columns[0],
+			"target (%d,%d) is blocked by an obstacle", x, y)
+	}
+
+	if len(parts) == 2 {
+		return position, nil, nil
+	}
+
+	directionCode, _ := utf8.DecodeRuneInString(parts[2])
+	if len(parts[2]) != 1 || (directionCode != 'N' && directionCode != 'S' && directionCode != 'E' && directionCode != 'W') {
+		return mars.Position{}, nil, newParseError(FieldDirection, targetInput, columns[2],
+			"invalid direction '%s': must be N, S, E, or W", parts[2])
+	}
+
+	direction := mars.DirectionFromCode(directionCode)
+	return position, &direction, nil
 }
 
-func CollectInstructionsFromInput(console *output.Console) (*[]mars.Instruction, error) {
-	instructionInput, err := console.Prompt("Enter movement instructions (R=Right, L=Left, F=Forward): ")
+// ParseObstacle
+// Parses an obstacle position in the form 'x y' or 'x,y' and adds it to the grid.
+func ParseObstacle(obstacleInput string, grid *mars.Grid) error {
+	parts, columns := fieldsWithColumns(strings.ReplaceAll(obstacleInput, ",", " "))
+
+	if len(parts) != 2 {
+		return newParseError(FieldObstacle, obstacleInput, 0,
+			"expected obstacle format 'x y' (e.g., '2 3'), got '%s'", obstacleInput)
+	}
+
+	x, err := strconv.Atoi(parts[0])
 	if err != nil {
-		console.Error("Failed to read instructions: %v", err)
-		return nil, err
+		return newParseError(FieldX, obstacleInput, columns[0],
+			"invalid obstacle x position: '%s' is not a number", parts[0])
 	}
-	instructionInput = strings.TrimSpace(strings.ToUpper(instructionInput))
 
-	if len(instructionInput) == 0 {
-		return nil, fmt.Errorf("instructions cannot be empty")
+	y, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return newParseError(FieldY, obstacleInput, columns[1],
+			"invalid obstacle y position: '%s' is not a number", parts[1])
 	}
-	if len(instructionInput) > 100 {
-		return nil, fmt.Errorf("instructions cannot be longer than 100 characters")
+
+	if x < 0 || y < 0 || x > int(grid.XSize) || y > int(grid.YSize) {
+		return newParseError(FieldObstacle, obstacleInput, columns[0],
+			"obstacle (%d,%d) is outside grid bounds (0-%d,0-%d)", x, y, grid.XSize, grid.YSize)
 	}
 
-	var instructions []mars.Instruction
+	grid.AddObstacle(mars.NewPosition(int8(x), int8(y)))
 
-	for i, char := range instructionInput {
-		var instruction mars.Instruction
+	return nil
+}
 
-		switch char {
-		case 'R':
-			instruction = mars.NewOrientationInstruction(mars.Right)
-		case 'L':
-			instruction = mars.NewOrientationInstruction(mars.Left)
-		case 'F':
-			instruction = mars.NewMovementInstruction(1)
-		default:
-			return nil, fmt.Errorf("invalid instruction '%c' at position %d: only R, L, F are allowed", char, i)
-		}
+// ParseEdge
+// Parses an edge policy name (lost, clamp, bounce or wrap) and sets it on the grid.
+func ParseEdge(edgeInput string, grid *mars.Grid) error {
+	policy, ok := mars.EdgePolicyFromName(strings.ToLower(strings.TrimSpace(edgeInput)))
+	if !ok {
+		return newParseError(FieldEdge, edgeInput, 0,
+			"invalid edge '%s': must be lost, clamp, bounce, or wrap", edgeInput)
+	}
 
-		console.Debug("New instruction: %v", instruction)
-		instructions = append(instructions, instruction)
+	grid.EdgePolicy = policy
+
+	return nil
+}
+
+// Parses a string of instruction codes into the instructions a rover can execute. The line may use groups and
+// define macros for its own use, see ParseProgram.
+func ParseInstructions(instructionInput string) ([]mars.Instruction, error) {
+	instructions, err := ParseProgram(instructionInput, Macros{})
+	if err != nil {
+		return nil, err
+	}
+	if len(instructions) == 0 {
+		return nil, newParseError(FieldInstructions, instructionInput, 0,
+			"instructions cannot be empty: the line only defines macros")
+	}
+	return instructions, nil
+}
+
+// parseGridSize
+// Parses the x and y boundaries found in gridInput at the given columns.
+func parseGridSize(gridInput string, parts []string, columns []int) (*mars.Grid, error) {
+	maxX, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return nil, newParseError(FieldX, gridInput, columns[0], "invalid x boundary: '%s' is not a number", parts[0])
 	}
 
-	console.Success("Instructions received: %s", instructionInput)
+	maxY, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, newParseError(FieldY, gridInput, columns[1], "invalid y boundary: '%s' is not a number", parts[1])
+	}
+
+	if maxX < 0 || maxY < 0 {
+		return nil, newParseError(FieldGrid, gridInput, 0, "grid boundaries must be positive (got %d,%d)", maxX, maxY)
+	}
+
+	if maxX == 0 || maxY == 0 {
+		return nil, newParseError(FieldGrid, gridInput, 0,
+			"grid must have non-zero dimensions (got %d,%d)", maxX, maxY)
+	}
+
+	return mars.NewGrid(uint8(maxX), uint8(maxY)), nil
+}
+
+// fieldsWithColumns
+// Splits the input around whitespace like strings.Fields, also returning the column each field starts at.
+func fieldsWithColumns(input string) ([]string, []int) {
+	var fields []string
+	var columns []int
+
+	start := -1
+	for i, char := range input {
+		if unicode.IsSpace(char) {
+			if start >= 0 {
+				fields = append(fields, input[start:i])
+				columns = append(columns, start)
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, input[start:])
+		columns = append(columns, start)
+	}
 
-	return &instructions, nil
+	return fields, columns
 }
@@ -2,6 +2,7 @@ package input
 
 import (
 	"bufio"
+	"errors"
 	"marster-bot/mars"
 	"marster-bot/output"
 	"strings"
@@ -165,6 +166,18 @@ func TestCollectRoverFromInput(t *testing.T) {
 			wantErr: true,
 			errMsg:  "exit",
 		},
+		{
+			name:    "Bare map is not a command",
+			input:   "map\n",
+			wantErr: true,
+			errMsg:  "expected format 'x y D'",
+		},
+		{
+			name:    "Bare undo is not a command",
+			input:   "undo rover\n",
+			wantErr: true,
+			errMsg:  "expected format 'x y D'",
+		},
 		{
 			name:    "Invalid format - too few parts",
 			input:   "1 2\n",
@@ -312,6 +325,46 @@ func TestCollectInstructionsFromInput(t *testing.T) {
 			wantErr:   false,
 			wantCount: 1,
 		},
+		{
+			name:      "Valid instructions - backwards and turn around",
+			input:     "BTF\n",
+			wantErr:   false,
+			wantCount: 3,
+			validateInst: func(inst []mars.Instruction) bool {
+				back, ok := inst[0].(*mars.MovementInstruction)
+				if !ok || back.Distance != -1 {
+					return false
+				}
+				turn, ok := inst[1].(*mars.RotationInstruction)
+				return ok && turn.Orientation == mars.Around
+			},
+		},
+		{
+			name:      "Valid instructions - distances",
+			input:     "F3RB12\n",
+			wantErr:   false,
+			wantCount: 3,
+			validateInst: func(inst []mars.Instruction) bool {
+				forward, ok := inst[0].(*mars.MovementInstruction)
+				if !ok || forward.Distance != 3 {
+					return false
+				}
+				back, ok := inst[2].(*mars.MovementInstruction)
+				return ok && back.Distance == -12
+			},
+		},
+		{
+			name:    "Zero distance",
+			input:   "RF0\n",
+			wantErr: true,
+			errMsg:  "invalid distance '0' at position 2",
+		},
+		{
+			name:    "Distance too large",
+			input:   "F200\n",
+			wantErr: true,
+			errMsg:  "invalid distance '200' at position 1",
+		},
 		{
 			name:    "Empty instructions",
 			input:   "\n",
@@ -373,4 +426,96 @@ func TestCollectInstructionsFromInput(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestParseErrors(t *testing.T) {
+	grid := mars.NewGrid(5, 5)
+
+	tests := []struct {
+		name   string
+		parse  func() error
+		field  string
+		column int
+		input  string
+	}{
+		{
+			name:   "Grid y boundary",
+			parse:  func() error { _, err := parseGridSize("5,x", []string{"5", "x"}, []int{0, 2}); return err },
+			field:  FieldY,
+			column: 2,
+			input:  "5,x",
+		},
+		{
+			name:   "Rover y position",
+			parse:  func() error { _, err := ParseRover("1  abc N", grid); return err },
+			field:  FieldY,
+			column: 3,
+			input:  "1  abc N",
+		},
+		{
+			name:   "Rover direction",
+			parse:  func() error { _, err := ParseRover("1 2 Q", grid); return err },
+			field:  FieldDirection,
+			column: 4,
+			input:  "1 2 Q",
+		},
+		{
+			name:   "Rover out of bounds",
+			parse:  func() error { _, err := ParseRover("1 9 N", grid); return err },
+			field:  FieldY,
+			column: 2,
+			input:  "1 9 N",
+		},
+		{
+			name:   "Instruction code",
+			parse:  func() error { _, err := ParseInstructions("FFRX"); return err },
+			field:  FieldInstructions,
+			column: 3,
+			input:  "FFRX",
+		},
+		{
+			name:   "Instruction code as typed",
+			parse:  func() error { _, err := ParseInstructions("  ffrx"); return err },
+			field:  FieldInstructions,
+			column: 5,
+			input:  "  ffrx",
+		},
+		{
+			name:   "Instruction distance",
+			parse:  func() error { _, err := ParseInstructions("RF0"); return err },
+			field:  FieldDistance,
+			column: 2,
+			input:  "RF0",
+		},
+		{
+			name:   "Obstacle position",
+			parse:  func() error { return ParseObstacle("2,q", grid) },
+			field:  FieldY,
+			column: 2,
+			input:  "2,q",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parseErr *ParseError
+			if err := tt.parse(); !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a ParseError, got %v", err)
+			}
+			if parseErr.Field != tt.field || parseErr.Column != tt.column || parseErr.Input != tt.input {
+				t.Errorf("Expected %s at column %d of %q, got %s at column %d of %q",
+					tt.field, tt.column, tt.input, parseErr.Field, parseErr.Column, parseErr.Input)
+			}
+		})
+	}
+
+	t.Run("Exit is a sentinel", func(t *testing.T) {
+		reader := bufio.NewReader(strings.NewReader("exit\n"))
+		console := output.NewConsole(*reader, false)
+
+		_, err := CollectRoverFromInput(console, grid)
+		if !errors.Is(err, ErrExit) {
+			t.Errorf("Expected ErrExit, got %v", err)
+		}
+	})
+}
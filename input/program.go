@@ -0,0 +1,474 @@
+package input
+
+import (
+	"marster-bot/mars"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxProgramLength
+// The most instructions a rover can be given at once, counted after groups and macros are expanded.
+const maxProgramLength = 100
+
+// Macros
+// Named instruction sequences defined with 'def NAME = ...' and called with '@NAME'. Definitions are kept between
+// lines, so a mission file or session can define a pattern once and call it for every rover.
+type Macros map[string][]mars.Instruction
+
+type tokenKind uint8
+
+const (
+	tokenEnd         tokenKind = iota
+	tokenInstruction           // a registered instruction code and its argument, e.g. F3
+	tokenCount                 // a repeat count in front of a group
+	tokenOpen                  // (
+	tokenClose                 // )
+	tokenCall                  // @NAME
+	tokenDef                   // DEF
+	tokenIf                    // IF
+	tokenElse                  // ELSE
+	tokenUntil                 // UNTIL
+	tokenName                  // the name after DEF, IF or UNTIL
+	tokenEquals                // =
+	tokenSeparator             // ;
+)
+
+// token
+// A lexeme of the instruction language, as typed, and the 0-based byte column it starts at.
+type token struct {
+	kind        tokenKind
+	text        string
+	column      int
+	instruction mars.Instruction
+	count       int
+}
+
+// lexProgram
+// Splits a line of the instruction language into tokens. Whitespace separates tokens and is otherwise ignored.
+// Codes and keywords are matched in any case, while columns and quoted text point into the line as it was typed.
+func lexProgram(programInput string) ([]token, error) {
+	var codes []rune
+	var offsets []int
+	for offset, char := range programInput {
+		codes = append(codes, unicode.ToUpper(char))
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(programInput))
+	raw := func(from, to int) string {
+		return programInput[offsets[from]:offsets[to]]
+	}
+
+	var tokens []token
+
+	for i := 0; i < len(codes); {
+		start := i
+		emit := func(kind tokenKind) {
+			tokens = append(tokens, token{kind: kind, text: raw(start, i), column: offsets[start]})
+		}
+		previous := tokenEnd
+		if len(tokens) > 0 {
+			previous = tokens[len(tokens)-1].kind
+		}
+
+		switch char := codes[i]; {
+		case unicode.IsSpace(char):
+			i++
+		case previous == tokenDef:
+			i += nameLength(codes[i:])
+			if i == start || unicode.IsDigit(codes[start]) {
+				return nil, newParseError(FieldInstructions, programInput, offsets[start],
+					"expected a macro name after DEF at position %d, e.g. DEF SWEEP = FFRFF", offsets[start])
+			}
+			emit(tokenName)
+		case previous == tokenIf || previous == tokenUntil:
+			i += nameLength(codes[i:])
+			if i == start {
+				return nil, newParseError(FieldInstructions, programInput, offsets[start],
+					"expected a condition at position %d: must be OFFGRID, SCENTED, BLOCKED or CLEAR", offsets[start])
+			}
+			emit(tokenName)
+		case isKeyword(codes[i:], "DEF"):
+			i += len("DEF")
+			emit(tokenDef)
+		case isKeyword(codes[i:], "IF"):
+			i += len("IF")
+			emit(tokenIf)
+		case isKeyword(codes[i:], "ELSE"):
+			i += len("ELSE")
+			emit(tokenElse)
+		case isKeyword(codes[i:], "UNTIL"):
+			i += len("UNTIL")
+			emit(tokenUntil)
+		case isInstructionCode(char):
+			kind, _ := mars.InstructionKindForCode(char)
+			instruction, used, err := kind.Parse(char, codes[i+1:])
+			if err != nil {
+				field, argument := FieldInstructions, "argument"
+				if kind.Argument != "" {
+					field, argument = kind.Argument, kind.Argument
+				}
+				return nil, newParseError(field, programInput, offsets[i+1], "invalid %s '%s' at position %d: %v",
+					argument, raw(i+1, i+1+used), offsets[i+1], err)
+			}
+			i += 1 + used
+			emit(tokenInstruction)
+			tokens[len(tokens)-1].instruction = instruction
+		case unicode.IsDigit(char):
+			for i < len(codes) && unicode.IsDigit(codes[i]) {
+				i++
+			}
+			count, err := strconv.Atoi(string(codes[start:i]))
+			if err != nil || count < 1 || count > maxProgramLength {
+				return nil, newParseError(FieldInstructions, programInput, offsets[start],
+					"invalid repeat count '%s' at position %d: must be between 1 and %d",
+					raw(start, i), offsets[start], maxProgramLength)
+			}
+			emit(tokenCount)
+			tokens[len(tokens)-1].count = count
+		case char == '@':
+			i += 1 + nameLength(codes[i+1:])
+			if i == start+1 {
+				return nil, newParseError(FieldInstructions, programInput, offsets[start],
+					"invalid instruction '@' at position %d: expected a macro name after '@', e.g. @SWEEP", offsets[start])
+			}
+			emit(tokenCall)
+		case char == '(':
+			i++
+			emit(tokenOpen)
+		case char == ')':
+			i++
+			emit(tokenClose)
+		case char == '=':
+			i++
+			emit(tokenEquals)
+		case char == ';':
+			i++
+			emit(tokenSeparator)
+		default:
+			return nil, newParseError(FieldInstructions, programInput, offsets[i],
+				"invalid instruction '%s' at position %d: only %s are allowed", raw(i, i+1), offsets[i], mars.InstructionCodes())
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, column: len(programInput)}), nil
+}
+
+func isInstructionCode(char rune) bool {
+	_, ok := mars.InstructionKindForCode(char)
+	return ok
+}
+
+func isNameRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_'
+}
+
+func nameLength(codes []rune) int {
+	length := 0
+	for length < len(codes) && isNameRune(codes[length]) {
+		length++
+	}
+	return length
+}
+
+// isKeyword
+// Reports whether the codes start with the keyword as a whole word.
+func isKeyword(codes []rune, keyword string) bool {
+	return len(codes) >= len(keyword) && string(codes[:len(keyword)]) == keyword &&
+		(len(codes) == len(keyword) || !isNameRune(codes[len(keyword)]))
+}
+
+// programParser
+// A recursive descent parser over the tokens of one line:
+//
+//	line       = statement { ";" statement }
+//	statement  = "DEF" name "=" sequence | sequence
+//	sequence   = { item }
+//	item       = instruction | count block | "@" name | "IF" condition block [ "ELSE" block ] | "UNTIL" condition block
+//	block      = "(" sequence ")"
+type programParser struct {
+	input  string
+	tokens []token
+	pos    int
+	macros Macros
+}
+
+func (p *programParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *programParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+// parseLine
+// Parses every statement on the line, returning the program and the names of the macros it defined.
+func (p *programParser) parseLine() ([]mars.Instruction, []string, error) {
+	program := []mars.Instruction{}
+	var defined []string
+
+	for {
+		if p.peek().kind == tokenDef {
+			name, err := p.parseDefinition()
+			if err != nil {
+				return nil, nil, err
+			}
+			defined = append(defined, name)
+		} else {
+			sequence, err := p.parseSequence(len(program))
+			if err != nil {
+				return nil, nil, err
+			}
+			program = append(program, sequence...)
+		}
+
+		switch t := p.next(); t.kind {
+		case tokenEnd:
+			return program, defined, nil
+		case tokenSeparator:
+		default:
+			return nil, nil, p.unexpected(t)
+		}
+	}
+}
+
+func (p *programParser) parseDefinition() (string, error) {
+	def := p.next()
+	name := p.next()
+	if name.kind != tokenName {
+		return "", newParseError(FieldInstructions, p.input, name.column,
+			"expected a macro name after DEF at position %d, e.g. DEF SWEEP = FFRFF", name.column)
+	}
+	if equals := p.next(); equals.kind != tokenEquals {
+		return "", newParseError(FieldInstructions, p.input, equals.column,
+			"expected '=' after DEF %s at position %d", name.text, equals.column)
+	}
+
+	body, err := p.parseSequence(0)
+	if err != nil {
+		return "", err
+	}
+	if len(body) == 0 {
+		return "", newParseError(FieldInstructions, p.input, def.column, "macro %s has no instructions", name.text)
+	}
+
+	// Macro names are matched in any case, like the codes they stand for
+	defined := strings.ToUpper(name.text)
+	p.macros[defined] = body
+	return defined, nil
+}
+
+// parseSequence
+// Parses items up to the end of the statement or block. Offset is the number of instructions the program already
+// has before the sequence, so the length limit is reported at the item that passes it. Instructions inside
+// conditionals and loops count towards the limit.
+func (p *programParser) parseSequence(offset int) ([]mars.Instruction, error) {
+	var sequence []mars.Instruction
+	size := 0
+
+	for {
+		t := p.peek()
+		var expansion []mars.Instruction
+
+		switch t.kind {
+		case tokenInstruction:
+			p.next()
+			expansion = []mars.Instruction{t.instruction}
+		case tokenCall:
+			p.next()
+			body, ok := p.macros[strings.ToUpper(strings.TrimPrefix(t.text, "@"))]
+			if !ok {
+				return nil, newParseError(FieldInstructions, p.input, t.column,
+					"undefined macro '%s' at position %d", t.text, t.column)
+			}
+			expansion = body
+		case tokenCount:
+			p.next()
+			group, err := p.parseGroup(t, offset+size)
+			if err != nil {
+				return nil, err
+			}
+			expansion = group
+		case tokenIf:
+			p.next()
+			conditional, err := p.parseConditional(t, offset+size)
+			if err != nil {
+				return nil, err
+			}
+			expansion = []mars.Instruction{conditional}
+		case tokenUntil:
+			p.next()
+			loop, err := p.parseLoop(t, offset+size)
+			if err != nil {
+				return nil, err
+			}
+			expansion = []mars.Instruction{loop}
+		default:
+			return sequence, nil
+		}
+
+		expansionSize := mars.InstructionCount(expansion)
+		if offset+size+expansionSize > maxProgramLength {
+			return nil, p.tooLong(t)
+		}
+		sequence = append(sequence, expansion...)
+		size += expansionSize
+	}
+}
+
+// parseBlock
+// Parses a parenthesised sequence following the token that introduced it.
+func (p *programParser) parseBlock(introducer token, offset int) ([]mars.Instruction, error) {
+	open := p.next()
+	if open.kind != tokenOpen {
+		return nil, newParseError(FieldInstructions, p.input, open.column,
+			"expected '(' after '%s' at position %d", introducer.text, open.column)
+	}
+
+	body, err := p.parseSequence(offset)
+	if err != nil {
+		return nil, err
+	}
+	if closing := p.next(); closing.kind != tokenClose {
+		if closing.kind == tokenEnd || closing.kind == tokenSeparator {
+			return nil, newParseError(FieldInstructions, p.input, open.column,
+				"group opened at position %d is never closed", open.column)
+		}
+		return nil, p.unexpected(closing)
+	}
+	if len(body) == 0 {
+		return nil, newParseError(FieldInstructions, p.input, open.column, "empty group at position %d", open.column)
+	}
+	return body, nil
+}
+
+// parseGroup
+// Parses the group following a repeat count and returns it repeated.
+func (p *programParser) parseGroup(count token, offset int) ([]mars.Instruction, error) {
+	if p.peek().kind != tokenOpen {
+		return nil, newParseError(FieldInstructions, p.input, count.column,
+			"invalid instruction '%s' at position %d: a repeat count must be followed by a group, e.g. 4(FFR)",
+			count.text, count.column)
+	}
+
+	body, err := p.parseBlock(count, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset+count.count*mars.InstructionCount(body) > maxProgramLength {
+		return nil, p.tooLong(count)
+	}
+	group := make([]mars.Instruction, 0, count.count*len(body))
+	for range count.count {
+		group = append(group, body...)
+	}
+	return group, nil
+}
+
+// parseCondition
+// Parses the condition named after IF or UNTIL.
+func (p *programParser) parseCondition(keyword token) (mars.Condition, error) {
+	name := p.next()
+	condition, ok := mars.ConditionFromName(strings.ToLower(name.text))
+	if name.kind != tokenName || !ok {
+		return condition, newParseError(FieldInstructions, p.input, name.column,
+			"unknown condition '%s' after %s at position %d: must be OFFGRID, SCENTED, BLOCKED or CLEAR",
+			name.text, strings.ToUpper(keyword.text), name.column)
+	}
+	return condition, nil
+}
+
+func (p *programParser) parseConditional(keyword token, offset int) (*mars.ConditionalInstruction, error) {
+	condition, err := p.parseCondition(keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	// The conditional itself counts as one instruction ahead of its branches
+	then, err := p.parseBlock(keyword, offset+1)
+	if err != nil {
+		return nil, err
+	}
+	conditional := &mars.ConditionalInstruction{Condition: condition, Then: then}
+
+	if p.peek().kind == tokenElse {
+		elseToken := p.next()
+		otherwise, err := p.parseBlock(elseToken, offset+1+mars.InstructionCount(then))
+		if err != nil {
+			return nil, err
+		}
+		conditional.Else = otherwise
+	}
+	return conditional, nil
+}
+
+func (p *programParser) parseLoop(keyword token, offset int) (*mars.LoopInstruction, error) {
+	condition, err := p.parseCondition(keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlock(keyword, offset+1)
+	if err != nil {
+		return nil, err
+	}
+	return &mars.LoopInstruction{Until: condition, Body: body}, nil
+}
+
+func (p *programParser) unexpected(t token) error {
+	return newParseError(FieldInstructions, p.input, t.column, "unexpected '%s' at position %d", t.text, t.column)
+}
+
+func (p *programParser) tooLong(t token) error {
+	return newParseError(FieldInstructions, p.input, t.column,
+		"instructions cannot expand to more than %d instructions: '%s' at position %d passes the limit",
+		maxProgramLength, t.text, t.column)
+}
+
+// ParseProgram
+// Parses a line of the instruction language into the instructions a rover can execute. Besides plain codes like
+// 'FFRF3', a line may repeat a group with a count ('4(FFR)'), define a macro ('DEF SWEEP = FFFFRFR'), call one
+// ('@SWEEP'), branch on what the rover senses ahead ('IF BLOCKED (R) ELSE (F)'), loop until it senses something
+// ('UNTIL BLOCKED (F)') and separate statements with ';'. Definitions are added to macros; a line that only defines
+// macros returns no instructions. The length limit applies to the expanded program.
+func ParseProgram(programInput string, macros Macros) ([]mars.Instruction, error) {
+	program, _, err := parseProgram(programInput, macros)
+	return program, err
+}
+
+// parseProgram
+// Parses the line like ParseProgram, also returning the names of the macros it defined. Macros are only changed if
+// the whole line parses.
+func parseProgram(programInput string, macros Macros) ([]mars.Instruction, []string, error) {
+	if strings.TrimSpace(programInput) == "" {
+		return nil, nil, newParseError(FieldInstructions, programInput, 0, "instructions cannot be empty")
+	}
+
+	tokens, err := lexProgram(programInput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staged := make(Macros, len(macros))
+	for name, body := range macros {
+		staged[name] = body
+	}
+	parser := &programParser{input: programInput, tokens: tokens, macros: staged}
+	program, defined, err := parser.parseLine()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(program) == 0 && len(defined) == 0 {
+		return nil, nil, newParseError(FieldInstructions, programInput, 0, "instructions cannot be empty")
+	}
+
+	for _, name := range defined {
+		macros[name] = staged[name]
+	}
+	return program, defined, nil
+}
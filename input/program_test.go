@@ -0,0 +1,210 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"marster-bot/mars"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestParseProgram(t *testing.T) {
+	code := mars.ProgramCode
+
+	tests := []struct {
+		name   string
+		input  string
+		macros Macros
+		want   string
+	}{
+		{name: "Plain codes", input: "FF3RLBT", want: "FF3RLBT"},
+		{name: "Counted group", input: "4(FFR)", want: "FFRFFRFFRFFR"},
+		{name: "Nested groups", input: "2(F 2(R))", want: "FRRFRR"},
+		{name: "Group with distances", input: "2(F3B)", want: "F3BF3B"},
+		{name: "Lowercase and spaces", input: " 2(f r) l ", want: "FRFRL"},
+		{name: "Definition and call", input: "def sq = 4(RF); @sq F", want: "RFRFRFRFF"},
+		{name: "Call in a group", input: "def turn = RR; 3(@turn)", want: "RRRRRR"},
+		{name: "Call from earlier line", input: "@sweep L", macros: Macros{"SWEEP": {mars.NewMovementInstruction(2)}}, want: "F2L"},
+		{name: "Redefinition", input: "def a = F; def a = R; @a", want: "R"},
+		{name: "Limit applies to expansion", input: "10(10(F))", want: strings.Repeat("F", 100)},
+		{name: "Distances count once", input: "F127", want: "F127"},
+		{name: "Conditional", input: "if blocked (R) else (F2)", want: "IF BLOCKED (R) ELSE (F2)"},
+		{name: "Conditional without else", input: "F if offgrid (T) F", want: "FIF OFFGRID (T)F"},
+		{name: "Loop", input: "until blocked (F) L", want: "UNTIL BLOCKED (F)L"},
+		{name: "Nested sensing", input: "2(until clear (R) F)", want: "UNTIL CLEAR (R)FUNTIL CLEAR (R)F"},
+		{name: "Sensing in a macro", input: "def hug = if scented (R) else (F); 2(@hug)", want: "IF SCENTED (R) ELSE (F)IF SCENTED (R) ELSE (F)"},
+		{name: "Reparses its own code", input: "FIF OFFGRID (T)F", want: "FIF OFFGRID (T)F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			macros := tt.macros
+			if macros == nil {
+				macros = Macros{}
+			}
+
+			instructions, err := ParseProgram(tt.input, macros)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := code(instructions); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Definitions are kept", func(t *testing.T) {
+		macros := Macros{}
+		instructions, err := ParseProgram("DEF sweep = 2(FR)", macros)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(instructions) != 0 {
+			t.Errorf("Expected no instructions from a definition, got %d", len(instructions))
+		}
+		if got := code(macros["SWEEP"]); got != "FRFR" {
+			t.Errorf("Expected SWEEP to expand to FRFR, got %q", got)
+		}
+	})
+
+	t.Run("Failed line defines nothing", func(t *testing.T) {
+		macros := Macros{}
+		if _, err := ParseProgram("def a = F; @b", macros); err == nil {
+			t.Fatal("Expected error, got nil")
+		}
+		if len(macros) != 0 {
+			t.Errorf("Expected no macros, got %v", macros)
+		}
+	})
+}
+
+func TestParseProgramErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		field  string
+		column int
+		errMsg string
+	}{
+		{"Empty", "  ", FieldInstructions, 0, "instructions cannot be empty"},
+		{"Invalid code", "2(FX)", FieldInstructions, 3, "invalid instruction 'X' at position 3"},
+		{"Invalid code after leading spaces", "  2(fx)", FieldInstructions, 5, "invalid instruction 'x' at position 5"},
+		{"Undefined macro after leading spaces", " f @sweep", FieldInstructions, 3, "undefined macro '@sweep' at position 3"},
+		{"Invalid distance", "2(F0)", FieldDistance, 3, "invalid distance '0' at position 3"},
+		{"Count without group", "RR3", FieldInstructions, 2, "a repeat count must be followed by a group"},
+		{"Zero count", "0(F)", FieldInstructions, 0, "invalid repeat count '0' at position 0"},
+		{"Unclosed group", "F 2(FR", FieldInstructions, 3, "group opened at position 3 is never closed"},
+		{"Unopened group", "FR)", FieldInstructions, 2, "unexpected ')' at position 2"},
+		{"Empty group", "3()", FieldInstructions, 1, "empty group at position 1"},
+		{"Undefined macro", "F @sweep", FieldInstructions, 2, "undefined macro '@sweep' at position 2"},
+		{"Call without name", "F@", FieldInstructions, 1, "invalid instruction '@' at position 1"},
+		{"Recursive macro", "def a = F @a", FieldInstructions, 10, "undefined macro '@a' at position 10"},
+		{"Definition without name", "def = F", FieldInstructions, 4, "expected a macro name after DEF at position 4"},
+		{"Definition without equals", "def a F", FieldInstructions, 6, "expected '=' after DEF a at position 6"},
+		{"Empty definition", "F; def a =", FieldInstructions, 3, "macro a has no instructions"},
+		{"Too long", "FF 50(FR)", FieldInstructions, 3, "cannot expand to more than 100 instructions: '50' at position 3"},
+		{"Too long after call", "def a = 50(FR); F @a", FieldInstructions, 18, "'@a' at position 18 passes the limit"},
+		{"Unknown condition", "if rocky (R)", FieldInstructions, 3, "unknown condition 'rocky' after IF at position 3"},
+		{"Condition missing", "until (F)", FieldInstructions, 6, "expected a condition at position 6"},
+		{"Conditional without block", "if clear F", FieldInstructions, 9, "expected '(' after 'if' at position 9"},
+		{"Else without block", "if clear (F) else R", FieldInstructions, 18, "expected '(' after 'else' at position 18"},
+		{"Else without if", "F else (R)", FieldInstructions, 2, "unexpected 'else' at position 2"},
+		{"Empty loop", "until clear ()", FieldInstructions, 12, "empty group at position 12"},
+		{"Invalid code after a multi-byte character", "é(F)", FieldInstructions, 0, "invalid instruction 'é' at position 0"},
+		{"Column counts bytes", "2(ñ) FX", FieldInstructions, 2, "invalid instruction 'ñ' at position 2"},
+		{"Column after a multi-byte character", "def né = F x", FieldInstructions, 12, "invalid instruction 'x' at position 12"},
+		{"Branches count towards the limit", "98(F) if clear (RR)", FieldInstructions, 17, "'R' at position 17 passes the limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseProgram(tt.input, Macros{})
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Expected a ParseError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+			if parseErr.Field != tt.field || parseErr.Column != tt.column {
+				t.Errorf("Expected %s at column %d, got %s at column %d", tt.field, tt.column, parseErr.Field, parseErr.Column)
+			}
+		})
+	}
+
+	t.Run("ParseInstructions needs instructions", func(t *testing.T) {
+		if _, err := ParseInstructions("def a = F"); err == nil || !strings.Contains(err.Error(), "only defines macros") {
+			t.Errorf("Expected an error for a line that only defines macros, got %v", err)
+		}
+	})
+}
+
+// waitInstruction
+// Keeps the rover where it is for a number of turns, standing in for an instruction registered by another package.
+type waitInstruction struct {
+	mars.InstructionBase
+	Turns int
+}
+
+func (w *waitInstruction) String() string {
+	return "Wait"
+}
+
+func (w *waitInstruction) Code() string {
+	return "W" + strconv.Itoa(w.Turns)
+}
+
+func TestParseProgramRegisteredInstruction(t *testing.T) {
+	err := mars.RegisterInstruction(mars.InstructionKind{
+		Name:     "wait",
+		Codes:    []rune{'W'},
+		Usage:    "W=Wait",
+		Argument: "turns",
+		Parse: func(code rune, args []rune) (mars.Instruction, int, error) {
+			if len(args) == 0 || !unicode.IsDigit(args[0]) {
+				return nil, 0, fmt.Errorf("expected a number of turns")
+			}
+			return &waitInstruction{Turns: int(args[0] - '0')}, 1, nil
+		},
+		Instruction: &waitInstruction{},
+		Execute:     func(rover *mars.Rover, instruction mars.Instruction) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer mars.UnregisterInstruction("wait")
+
+	t.Run("Parses the code", func(t *testing.T) {
+		instructions, err := ParseProgram("2(W3 F) @pause", Macros{"PAUSE": {&waitInstruction{Turns: 1}}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := mars.ProgramCode(instructions); got != "W3FW3FW1" {
+			t.Errorf("Expected W3FW3FW1, got %q", got)
+		}
+	})
+
+	t.Run("Reports the argument", func(t *testing.T) {
+		_, err := ParseProgram("F W", Macros{})
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Expected a ParseError, got %v", err)
+		}
+		if parseErr.Field != "turns" || parseErr.Column != 3 {
+			t.Errorf("Expected turns at column 3, got %s at column %d", parseErr.Field, parseErr.Column)
+		}
+		if !strings.Contains(err.Error(), "invalid turns '' at position 3: expected a number of turns") {
+			t.Errorf("Expected an invalid turns error, got %q", err.Error())
+		}
+	})
+
+	t.Run("Lists the code", func(t *testing.T) {
+		_, err := ParseProgram("X", Macros{})
+		if err == nil || !strings.Contains(err.Error(), "only R, L, T, F, B, W are allowed") {
+			t.Errorf("Expected the registered codes in the error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package input
+
+import (
+	"fmt"
+	"marster-bot/mars"
+	"marster-bot/output"
+)
+
+var directionCodes = map[string]mars.Direction{
+	"N": mars.North,
+	"E": mars.East,
+	"S": mars.South,
+	"W": mars.West,
+}
+
+// ParseScentMap
+// Decodes the scents in a saved scent map for the grid. The map must have been saved from a grid of the same size,
+// since a scent only protects the edge it was left on.
+func ParseScentMap(scentMap output.ScentMapRecord, grid *mars.Grid) ([]mars.Scent, error) {
+	if scentMap.MaxX != int(grid.XSize) || scentMap.MaxY != int(grid.YSize) {
+		return nil, fmt.Errorf("scent map is for a %d,%d grid, not %d,%d",
+			scentMap.MaxX, scentMap.MaxY, grid.XSize, grid.YSize)
+	}
+
+	scents := make([]mars.Scent, 0, len(scentMap.Scents))
+	for _, scent := range scentMap.Scents {
+		if scent.X < 0 || scent.Y < 0 || scent.X > int(grid.XSize) || scent.Y > int(grid.YSize) {
+			return nil, fmt.Errorf("scent at (%d,%d) is outside the grid", scent.X, scent.Y)
+		}
+		direction, ok := directionCodes[scent.Direction]
+		if !ok {
+			return nil, fmt.Errorf("scent at (%d,%d) has invalid direction '%s': must be N, S, E, or W",
+				scent.X, scent.Y, scent.Direction)
+		}
+
+		parsed := mars.Scent{Position: mars.NewPosition(int8(scent.X), int8(scent.Y)), Direction: direction}
+		if scent.Instruction != "" {
+			instructions, err := ParseInstructions(scent.Instruction)
+			if err != nil || len(instructions) != 1 {
+				return nil, fmt.Errorf("scent at (%d,%d) has invalid instruction '%s'", scent.X, scent.Y, scent.Instruction)
+			}
+			parsed.Instruction = instructions[0]
+		}
+		scents = append(scents, parsed)
+	}
+	return scents, nil
+}
+
+// LoadScentMap
+// Adds the scents from a saved scent map to the grid. Every scent is checked before any is added, so the grid is
+// left unchanged if the map is invalid.
+func LoadScentMap(grid *mars.Grid, scentMap output.ScentMapRecord) error {
+	scents, err := ParseScentMap(scentMap, grid)
+	if err != nil {
+		return err
+	}
+
+	for _, scent := range scents {
+		grid.LeaveScent(scent)
+	}
+	return nil
+}
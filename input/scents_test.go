@@ -0,0 +1,81 @@
+package input
+
+import (
+	"marster-bot/mars"
+	"marster-bot/output"
+	"strings"
+	"testing"
+)
+
+func TestLoadScentMap(t *testing.T) {
+	t.Run("Adds every scent with its heading and instruction", func(t *testing.T) {
+		grid := mars.NewGrid(5, 3)
+		scentMap := output.ScentMapRecord{MaxX: 5, MaxY: 3, Scents: []output.ScentRecord{
+			{X: 3, Y: 3, Direction: "N", Instruction: "F"},
+			{X: 0, Y: 1, Direction: "W"},
+		}}
+
+		if err := LoadScentMap(grid, scentMap); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scents := grid.ExitScents()
+		if len(scents) != 2 || scents[0].Direction != mars.North || mars.InstructionCode(scents[0].Instruction) != "F" {
+			t.Errorf("Expected both scents to be loaded, got %+v", scents)
+		}
+	})
+
+	t.Run("Rejects invalid scent maps", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			scentMap output.ScentMapRecord
+			wantErr  string
+		}{
+			{
+				name:     "different grid size",
+				scentMap: output.ScentMapRecord{MaxX: 4, MaxY: 3},
+				wantErr:  "scent map is for a 4,3 grid, not 5,3",
+			},
+			{
+				name:     "scent outside the grid",
+				scentMap: output.ScentMapRecord{MaxX: 5, MaxY: 3, Scents: []output.ScentRecord{{X: 6, Y: 0, Direction: "E"}}},
+				wantErr:  "outside the grid",
+			},
+			{
+				name:     "unknown heading",
+				scentMap: output.ScentMapRecord{MaxX: 5, MaxY: 3, Scents: []output.ScentRecord{{X: 5, Y: 0, Direction: "Q"}}},
+				wantErr:  "invalid direction 'Q'",
+			},
+			{
+				name: "unknown instruction",
+				scentMap: output.ScentMapRecord{MaxX: 5, MaxY: 3,
+					Scents: []output.ScentRecord{{X: 5, Y: 0, Direction: "E", Instruction: "FF"}}},
+				wantErr: "invalid instruction 'FF'",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := LoadScentMap(mars.NewGrid(5, 3), tt.scentMap)
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+			})
+		}
+	})
+
+	t.Run("Leaves the grid unchanged when a later scent is invalid", func(t *testing.T) {
+		grid := mars.NewGrid(5, 3)
+		scentMap := output.ScentMapRecord{MaxX: 5, MaxY: 3, Scents: []output.ScentRecord{
+			{X: 3, Y: 3, Direction: "N"},
+			{X: 5, Y: 0, Direction: "E"},
+			{X: 9, Y: 9, Direction: "E"},
+		}}
+
+		if err := LoadScentMap(grid, scentMap); err == nil {
+			t.Fatal("Expected an error for the scent outside the grid")
+		}
+		if scents := grid.ExitScents(); len(scents) != 0 {
+			t.Errorf("Expected no scents to be loaded, got %v", scents)
+		}
+	})
+}
@@ -3,66 +3,105 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"marster-bot/engine"
 	"marster-bot/input"
-	"marster-bot/mars"
 	"marster-bot/output"
 	"os"
 
 	"github.com/urfave/cli/v3"
 )
 
-func processRover(console *output.Console, grid *mars.Grid, roverNum int) error {
+func processRover(console *output.Console, sess *session, roverNum int, showMap bool) error {
+	simulation := sess.history.Simulation
 	console.Blank()
 	console.Header(fmt.Sprintf("Rover #%d", roverNum))
 	console.Divider()
 
-	rover, err := input.CollectRoverFromInput(console, grid)
+	rover, err := input.CollectRoverFromInput(console, simulation.Grid)
 	if err != nil {
 		return err
 	}
 
-	instructions, err := input.CollectInstructionsFromInput(console)
+	instructions, err := input.CollectProgramFromInput(console, sess.macros)
 	if err != nil {
 		return err
 	}
+	if sess.optimize {
+		optimization := engine.Optimize(*instructions)
+		console.Info("Optimized: %s", optimization)
+		instructions = &optimization.Instructions
+	}
+
+	// A meta-command may have loaded another session while the instructions were being entered
+	history := sess.history
+	simulation = history.Simulation
+	if rover.Grid != simulation.Grid {
+		return fmt.Errorf("a new session was loaded, enter the rover again")
+	}
 
-	// Process the rover (placeholder for now)
-	console.Debug("No. instructions: %d", len(*instructions))
 	console.Blank()
 	console.Info("Processing rover movements...")
 
-	for _, instruction := range *instructions {
-		console.Debug("Current position: %d %d %s", rover.Position.X, rover.Position.Y, rover.Direction)
-		console.Debug("Processing instruction: %v", instruction)
-		err := rover.Instruct(console, instruction)
+	if showMap {
+		defer func() {
+			console.Blank()
+			console.Map(simulation.Map(), output.MapView{})
+		}()
+	}
 
-		if err != nil {
-			return err
+	for _, result := range history.Run(rover, *instructions) {
+		for _, collision := range result.Collisions {
+			console.Warning("Rover #%d ran into rover #%d at %s", result.Number, collision.Other, collision.Position.String())
 		}
-	}
 
-	console.Success("Final position:  %d %d %s", rover.Position.X, rover.Position.Y, rover.Direction)
+		switch {
+		case result.Obstacle != nil:
+			console.Warning("Rover #%d was stopped by an obstacle at %s", result.Number, result.Obstacle.String())
+		case result.Collision != nil:
+			console.Warning("Rover #%d was aborted: %v", result.Number, result.Collision)
+		case result.Loop != nil:
+			console.Warning("Rover #%d was stopped: %v", result.Number, result.Loop)
+		case result.Err != nil:
+			return result.Err
+		}
+		console.Success("Final position:  %s", result)
+	}
 
 	return nil
 }
 
-func runRoverSimulation(console *output.Console) error {
+func runRoverSimulation(sess *session, showMap bool) error {
+	console, options := sess.console, sess.options
 	console.HeaderWithBorder("Mars Rover Explorer")
 	console.Blank()
 
+	dispatcher := input.NewDispatcher(console)
+	dispatcher.Register(sess.commands()...)
+	console.SetCommandHandler(dispatcher.Handle)
+
 	grid, err := input.CollectGridFromInput(console)
 
 	if err != nil {
 		return err
 	}
 
-	roverNum := 1
+	if err := options.apply(grid); err != nil {
+		return err
+	}
+
+	sess.start(grid)
+
 	for {
-		err := processRover(console, grid, roverNum)
+		// ':undo rover' and ':redo rover' change how many rovers have run, so the next number is worked out each time
+		roverNum := len(sess.history.Simulation.Results()) + 1
+		err := processRover(console, sess, roverNum, showMap)
 		if err != nil {
-			if err.Error() == "exit" {
+			// Running out of input ends the session like 'exit', so piped and replayed sessions finish
+			if errors.Is(err, input.ErrExit) || errors.Is(err, io.EOF) {
 				console.Blank()
 				console.Success("Thank you for using Mars Rover Explorer!")
 				break
@@ -70,37 +109,84 @@ func runRoverSimulation(console *output.Console) error {
 			console.Error("Error processing rover #%d: %v", roverNum, err)
 
 			// If there was an error with this rover, ask if they want to try again
-			response, _ := console.Prompt("Would you like to add another rover? (Y/n): ")
-			if response != "" && response != "y" {
+			response, err := console.Prompt("Would you like to add another rover? (Y/n): ")
+			if err != nil || (response != "" && response != "y") {
 				console.Success("Thank you for using Mars Rover Explorer!")
 				break
 			}
 		}
 
-		roverNum++
 		console.Blank()
 		console.Divider()
 	}
 
-	return nil
+	return options.save(sess.history.Simulation.Grid)
 }
 
 func main() {
 	app := &cli.Command{
 		Name:  "Marster Bot",
 		Usage: "A Mars rover navigation simulator",
-		Flags: []cli.Flag{
+		// Obstacles are given as 'x,y', so commas must not split slice flags.
+		DisableSliceFlagSeparator: true,
+		Flags: append([]cli.Flag{
 			&cli.BoolFlag{
 				Name:  "debug",
 				Usage: "Enable debug output",
 				Value: false,
 			},
+			&cli.BoolFlag{
+				Name:  "map",
+				Usage: "Draw the grid map after each rover",
+			},
+			&cli.BoolFlag{
+				Name:  "optimize",
+				Usage: "Fold redundant turns and merge consecutive moves in each rover's instructions before it runs",
+			},
+			&cli.StringFlag{
+				Name:  "record",
+				Usage: "Record every answer and the state it leads to in `file`, for the replay command",
+			},
+		}, gridFlags()...),
+		Commands: []*cli.Command{
+			runCommand(),
+			planCommand(),
+			checkCommand(),
+			replayCommand(),
+			testCommand(),
+			serveCommand(),
 		},
 		Action: func(ctx context.Context, c *cli.Command) error {
+			options, err := gridOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+
 			debugMode := c.Bool("debug")
 			reader := bufio.NewReader(os.Stdin)
 			console := output.NewConsole(*reader, debugMode)
-			return runRoverSimulation(console)
+			sess := &session{console: console, options: options, optimize: c.Bool("optimize")}
+			if c.String("record") == "" {
+				return runRoverSimulation(sess, c.Bool("map"))
+			}
+
+			file, err := os.Create(c.String("record"))
+			if err != nil {
+				return fmt.Errorf("--record: %w", err)
+			}
+			defer file.Close()
+
+			recorder, err := newTranscriptRecorder(sess, file)
+			if err != nil {
+				return fmt.Errorf("--record: %w", err)
+			}
+			console.SetPromptObserver(recorder)
+
+			err = runRoverSimulation(sess, c.Bool("map"))
+			if recordErr := recorder.finish(); recordErr != nil {
+				return fmt.Errorf("--record: %w", recordErr)
+			}
+			return err
 		},
 	}
 
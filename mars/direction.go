@@ -20,6 +20,9 @@ func (d Direction) Rotate(rotation Rotation) Direction {
 	case Left:
 		newValue := (d.value - 1 + 4) % 4
 		return DirectionFromValue(uint8(newValue))
+	case Around:
+		newValue := (d.value + 2) % 4
+		return DirectionFromValue(uint8(newValue))
 	default:
 		return d
 	}
@@ -58,6 +61,7 @@ func DirectionFromCode(code rune) Direction {
 type Rotation rune
 
 const (
-	Right Rotation = 'R'
-	Left  Rotation = 'L'
+	Right  Rotation = 'R'
+	Left   Rotation = 'L'
+	Around Rotation = 'T'
 )
@@ -0,0 +1,53 @@
+package mars
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownInstruction
+// Returned when a rover is given an instruction it does not know how to execute.
+var ErrUnknownInstruction = errors.New("unknown instruction")
+
+// LostError
+// Returned when a rover falls off the grid, carrying the last position it safely reached and its heading.
+type LostError struct {
+	Position  Position
+	Direction Direction
+}
+
+func (e *LostError) Error() string {
+	return fmt.Sprintf("rover fell off the grid at (%d, %d) facing %s", e.Position.X, e.Position.Y, e.Direction)
+}
+
+// ObstacleError
+// Returned when a rover is stopped by an obstacle under the ObstacleStop policy.
+type ObstacleError struct {
+	Position Position
+}
+
+func (e *ObstacleError) Error() string {
+	return fmt.Sprintf("obstacle at %s", e.Position.String())
+}
+
+// CollisionError
+// Returned when a rover runs into another rover under the CollisionAbort policy.
+type CollisionError struct {
+	Position Position
+	Rover    int
+	Other    int
+}
+
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("rover #%d collided with rover #%d at %s", e.Rover, e.Other, e.Position.String())
+}
+
+// LoopLimitError
+// Returned when a loop instruction's condition still does not hold after MaxLoopIterations passes.
+type LoopLimitError struct {
+	Until Condition
+}
+
+func (e *LoopLimitError) Error() string {
+	return fmt.Sprintf("loop until %s did not finish within %d passes", e.Until, MaxLoopIterations)
+}
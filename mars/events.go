@@ -0,0 +1,117 @@
+package mars
+
+import "sync"
+
+// Event
+// Something that happened on the grid. Events are published to every listener subscribed to the grid.
+type Event interface {
+	isEvent()
+}
+
+// InstructedEvent
+// A rover is about to execute an instruction.
+type InstructedEvent struct {
+	Rover       *Rover
+	Instruction Instruction
+}
+
+// RotatedEvent
+// A rover turned from one heading to another.
+type RotatedEvent struct {
+	Rover *Rover
+	From  Direction
+	To    Direction
+}
+
+// MovedEvent
+// A rover stepped from one cell to a neighbouring one.
+type MovedEvent struct {
+	Rover *Rover
+	From  Position
+	To    Position
+}
+
+// BlockedByScentEvent
+// A rover ignored a move off the grid because its cell was scented.
+type BlockedByScentEvent struct {
+	Rover     *Rover
+	Position  Position
+	Direction Direction
+}
+
+// BlockedByObstacleEvent
+// A rover's move was ended by an obstacle.
+type BlockedByObstacleEvent struct {
+	Rover    *Rover
+	Obstacle Position
+}
+
+// CollidedEvent
+// A rover tried to move into a cell occupied by another rover.
+type CollidedEvent struct {
+	Rover    *Rover
+	Other    *Rover
+	Position Position
+}
+
+// ReachedEdgeEvent
+// A rover tried to move off the grid in the given heading and was handled by the grid's EdgePolicy rather than
+// falling off.
+type ReachedEdgeEvent struct {
+	Rover     *Rover
+	Position  Position
+	Direction Direction
+	Policy    EdgePolicy
+}
+
+// FellOffEvent
+// A rover fell off the grid from its last safe cell.
+type FellOffEvent struct {
+	Rover     *Rover
+	Position  Position
+	Direction Direction
+}
+
+// ScentAddedEvent
+// A scent was left on the grid by a rover falling off in the given heading.
+type ScentAddedEvent struct {
+	Position  Position
+	Direction Direction
+}
+
+func (InstructedEvent) isEvent()        {}
+func (RotatedEvent) isEvent()           {}
+func (MovedEvent) isEvent()             {}
+func (BlockedByScentEvent) isEvent()    {}
+func (BlockedByObstacleEvent) isEvent() {}
+func (CollidedEvent) isEvent()          {}
+func (ReachedEdgeEvent) isEvent()       {}
+func (FellOffEvent) isEvent()           {}
+func (ScentAddedEvent) isEvent()        {}
+
+// Listener
+// Receives events published by a grid.
+type Listener interface {
+	OnEvent(event Event)
+}
+
+// ListenerFunc
+// Adapts a plain function to the Listener interface.
+type ListenerFunc func(event Event)
+
+func (f ListenerFunc) OnEvent(event Event) {
+	f(event)
+}
+
+// Recorder
+// A listener that keeps every event it receives, in order. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	Events []Event
+}
+
+func (r *Recorder) OnEvent(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Events = append(r.Events, event)
+}
@@ -0,0 +1,69 @@
+package mars
+
+import "testing"
+
+func TestGridEvents(t *testing.T) {
+	t.Run("Publishes moves, turns and falls in order", func(t *testing.T) {
+		grid := NewGrid(2, 2)
+		recorder := &Recorder{}
+		grid.Subscribe(recorder)
+
+		rover := NewRover(1, 1, North, grid)
+		rover.Instruct(NewOrientationInstruction(Right))
+		rover.Instruct(NewMovementInstruction(1))
+		rover.Instruct(NewMovementInstruction(1))
+
+		expected := []Event{
+			InstructedEvent{Rover: rover, Instruction: NewOrientationInstruction(Right)},
+			RotatedEvent{Rover: rover, From: North, To: East},
+			InstructedEvent{Rover: rover, Instruction: NewMovementInstruction(1)},
+			MovedEvent{Rover: rover, From: NewPosition(1, 1), To: NewPosition(2, 1)},
+			InstructedEvent{Rover: rover, Instruction: NewMovementInstruction(1)},
+			FellOffEvent{Rover: rover, Position: NewPosition(2, 1), Direction: East},
+			ScentAddedEvent{Position: NewPosition(2, 1), Direction: East},
+		}
+		if len(recorder.Events) != len(expected) {
+			t.Fatalf("Expected %d events, got %d: %v", len(expected), len(recorder.Events), recorder.Events)
+		}
+		for i, event := range recorder.Events {
+			if instructed, ok := event.(InstructedEvent); ok {
+				if _, ok := expected[i].(InstructedEvent); !ok || instructed.Rover != rover {
+					t.Errorf("Expected event %d to be %v, got %v", i, expected[i], event)
+				}
+				continue
+			}
+			if event != expected[i] {
+				t.Errorf("Expected event %d to be %v, got %v", i, expected[i], event)
+			}
+		}
+	})
+
+	t.Run("Publishes blocked moves", func(t *testing.T) {
+		grid := NewGrid(2, 2)
+		grid.AddScent(NewPosition(2, 2), North)
+		grid.AddObstacle(NewPosition(1, 2))
+		recorder := &Recorder{}
+		grid.Subscribe(recorder)
+
+		rover := NewRover(2, 2, North, grid)
+		rover.Move(1)
+		rover.Rotate(Left)
+		rover.Move(1)
+
+		var scented, obstructed bool
+		for _, event := range recorder.Events {
+			switch e := event.(type) {
+			case BlockedByScentEvent:
+				scented = e.Position == NewPosition(2, 2) && e.Direction == North
+			case BlockedByObstacleEvent:
+				obstructed = e.Obstacle == NewPosition(1, 2)
+			}
+		}
+		if !scented {
+			t.Error("Expected a scent-blocked event at (2,2) heading N")
+		}
+		if !obstructed {
+			t.Error("Expected an obstacle event at (1,2)")
+		}
+	})
+}
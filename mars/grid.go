@@ -1,9 +1,159 @@
 package mars
 
+import "sync"
+
+// ObstaclePolicy
+// Decides what happens when a rover tries to move into a blocked cell.
+type ObstaclePolicy uint8
+
+const (
+	// ObstacleSkip silently skips the rest of the blocked move and carries on with the next instruction.
+	ObstacleSkip ObstaclePolicy = iota
+	// ObstacleStop halts the rover in front of the obstacle and reports it.
+	ObstacleStop
+)
+
+var obstaclePolicyNames = map[ObstaclePolicy]string{
+	ObstacleSkip: "skip",
+	ObstacleStop: "stop",
+}
+
+func (p ObstaclePolicy) String() string {
+	return obstaclePolicyNames[p]
+}
+
+func ObstaclePolicyFromName(name string) (ObstaclePolicy, bool) {
+	for policy, policyName := range obstaclePolicyNames {
+		if policyName == name {
+			return policy, true
+		}
+	}
+	return ObstacleSkip, false
+}
+
+// CollisionPolicy
+// Decides what happens when a rover tries to move into a cell occupied by another rover.
+type CollisionPolicy uint8
+
+const (
+	// CollisionBlock skips the rest of the move, leaving the rover in front of the other rover.
+	CollisionBlock CollisionPolicy = iota
+	// CollisionAbort halts the rover and reports the collision.
+	CollisionAbort
+	// CollisionAllow lets the rover share the cell, recording the collision as a warning.
+	CollisionAllow
+)
+
+var collisionPolicyNames = map[CollisionPolicy]string{
+	CollisionBlock: "block",
+	CollisionAbort: "abort",
+	CollisionAllow: "allow",
+}
+
+func (p CollisionPolicy) String() string {
+	return collisionPolicyNames[p]
+}
+
+func CollisionPolicyFromName(name string) (CollisionPolicy, bool) {
+	for policy, policyName := range collisionPolicyNames {
+		if policyName == name {
+			return policy, true
+		}
+	}
+	return CollisionBlock, false
+}
+
+// EdgePolicy
+// Decides what happens when a rover tries to move off the edge of the grid.
+type EdgePolicy uint8
+
+const (
+	// EdgeLost loses the rover off the grid, leaving a scent that protects later rovers.
+	EdgeLost EdgePolicy = iota
+	// EdgeClamp treats the edge as a wall: the rover stays where it is and the rest of the move is skipped.
+	EdgeClamp
+	// EdgeBounce turns the rover around at the edge; the blocked step is spent turning and any remaining steps
+	// carry on in the new heading.
+	EdgeBounce
+	// EdgeWrap joins opposite edges, so a rover leaving the east edge re-enters on the west.
+	EdgeWrap
+)
+
+var edgePolicyNames = map[EdgePolicy]string{
+	EdgeLost:   "lost",
+	EdgeClamp:  "clamp",
+	EdgeBounce: "bounce",
+	EdgeWrap:   "wrap",
+}
+
+func (p EdgePolicy) String() string {
+	return edgePolicyNames[p]
+}
+
+func EdgePolicyFromName(name string) (EdgePolicy, bool) {
+	for policy, policyName := range edgePolicyNames {
+		if policyName == name {
+			return policy, true
+		}
+	}
+	return EdgeLost, false
+}
+
+// ScentMode
+// Decides which moves off the grid a scent protects against.
+type ScentMode uint8
+
+const (
+	// ScentByExit only ignores moves off the grid in the heading the lost rover fell, so a scent on a corner does
+	// not block the other edge.
+	ScentByExit ScentMode = iota
+	// ScentByPosition ignores every move off the grid from a scented cell, whatever the heading.
+	ScentByPosition
+)
+
+var scentModeNames = map[ScentMode]string{
+	ScentByExit:     "exit",
+	ScentByPosition: "position",
+}
+
+func (m ScentMode) String() string {
+	return scentModeNames[m]
+}
+
+func ScentModeFromName(name string) (ScentMode, bool) {
+	for mode, modeName := range scentModeNames {
+		if modeName == name {
+			return mode, true
+		}
+	}
+	return ScentByExit, false
+}
+
+// Scent
+// Marks the cell a rover fell off the grid from and the heading it was moving in when it fell. Instruction is the
+// instruction that caused the loss, when it is known.
+type Scent struct {
+	Position    Position
+	Direction   Direction
+	Instruction Instruction
+}
+
+// Grid
+// The plateau rovers explore. Its scents, obstacles, occupants and listeners are safe for concurrent use; its size
+// and policies are expected to be set up before any rover moves.
 type Grid struct {
 	XSize            uint8
 	YSize            uint8
+	ObstaclePolicy   ObstaclePolicy
+	CollisionPolicy  CollisionPolicy
+	EdgePolicy       EdgePolicy
+	ScentMode        ScentMode
+	mu               sync.RWMutex
 	scentedPositions *PositionSet
+	scents           []Scent
+	obstacles        *PositionSet
+	occupants        map[Position][]*Rover
+	listeners        []Listener
 }
 
 func NewGrid(xSize, ySize uint8) *Grid {
@@ -11,9 +161,78 @@ func NewGrid(xSize, ySize uint8) *Grid {
 		XSize:            xSize,
 		YSize:            ySize,
 		scentedPositions: NewPositionSet(),
+		obstacles:        NewPositionSet(),
+		occupants:        make(map[Position][]*Rover),
 	}
 }
 
+// Subscribe
+// Registers a listener for every event published on the grid, including those of the rovers on it.
+func (m *Grid) Subscribe(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// Publish
+// Sends the event to every listener subscribed to the grid. Listeners must be safe for concurrent use if rovers on
+// the grid move concurrently.
+func (m *Grid) Publish(event Event) {
+	m.mu.RLock()
+	listeners := m.listeners
+	m.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener.OnEvent(event)
+	}
+}
+
+// Clone
+// Returns a copy of the grid with its own scents, obstacles and occupants, and no listeners. Changes to the clone
+// are not seen by the original.
+func (m *Grid) Clone() *Grid {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	clone := &Grid{
+		XSize:            m.XSize,
+		YSize:            m.YSize,
+		ObstaclePolicy:   m.ObstaclePolicy,
+		CollisionPolicy:  m.CollisionPolicy,
+		EdgePolicy:       m.EdgePolicy,
+		ScentMode:        m.ScentMode,
+		scentedPositions: m.scentedPositions.Clone(),
+		scents:           append([]Scent(nil), m.scents...),
+		obstacles:        m.obstacles.Clone(),
+		occupants:        make(map[Position][]*Rover, len(m.occupants)),
+	}
+	for pos, rovers := range m.occupants {
+		clone.occupants[pos] = append([]*Rover(nil), rovers...)
+	}
+	return clone
+}
+
+// Restore
+// Replaces the grid's scents, obstacles and occupants with copies of a snapshot's, keeping its own listeners and
+// policies. Rolls the grid back to a state captured with Clone.
+func (m *Grid) Restore(snapshot *Grid) {
+	restored := snapshot.Clone()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scentedPositions.Clear()
+	for _, pos := range restored.scentedPositions.Keys() {
+		m.scentedPositions.Add(pos)
+	}
+	m.obstacles.Clear()
+	for _, pos := range restored.obstacles.Keys() {
+		m.obstacles.Add(pos)
+	}
+	m.scents = restored.scents
+	m.occupants = restored.occupants
+}
+
 func (m *Grid) IsScented(pos Position) bool {
 	return m.scentedPositions.Has(pos)
 }
@@ -22,8 +241,133 @@ func (m *Grid) IsScentedXY(x, y int8) bool {
 	return m.scentedPositions.Has(NewPosition(x, y))
 }
 
-func (m *Grid) AddScent(pos Position) {
-	m.scentedPositions.Add(pos)
+// IsExitScented
+// Reports whether a move off the grid from the position in the given heading is protected by a scent, according to
+// the grid's ScentMode.
+func (m *Grid) IsExitScented(pos Position, heading Direction) bool {
+	if m.ScentMode == ScentByPosition {
+		return m.IsScented(pos)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, scent := range m.scents {
+		if scent.Position == pos && scent.Direction == heading {
+			return true
+		}
+	}
+	return false
+}
+
+// AddScent
+// Leaves a scent at the position for a rover that fell off while moving in the given heading.
+func (m *Grid) AddScent(pos Position, heading Direction) {
+	m.LeaveScent(Scent{Position: pos, Direction: heading})
+}
+
+// LeaveScent
+// Adds the scent to the grid, reporting whether it was new. A scent for an exit that is already scented is not
+// added twice.
+func (m *Grid) LeaveScent(scent Scent) bool {
+	m.mu.Lock()
+	for _, existing := range m.scents {
+		if existing.Position == scent.Position && existing.Direction == scent.Direction {
+			m.mu.Unlock()
+			return false
+		}
+	}
+
+	m.scentedPositions.Add(scent.Position)
+	m.scents = append(m.scents, scent)
+	m.mu.Unlock()
+
+	m.Publish(ScentAddedEvent{Position: scent.Position, Direction: scent.Direction})
+	return true
+}
+
+// ClearScents
+// Removes every scent from the grid, so every exit is unprotected again.
+func (m *Grid) ClearScents() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scentedPositions.Clear()
+	m.scents = nil
+}
+
+func (m *Grid) Scents() []Position {
+	return m.scentedPositions.Keys()
+}
+
+// ExitScents
+// Returns every scent along with the heading it was left from, in the order they were added.
+func (m *Grid) ExitScents() []Scent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Scent(nil), m.scents...)
+}
+
+func (m *Grid) AddObstacle(pos Position) {
+	m.obstacles.Add(pos)
+}
+
+func (m *Grid) IsObstacle(pos Position) bool {
+	return m.obstacles.Has(pos)
+}
+
+func (m *Grid) Obstacles() []Position {
+	return m.obstacles.Keys()
+}
+
+// Occupy
+// Places the rover on the grid at its current position so other rovers can collide with it.
+func (m *Grid) Occupy(rover *Rover) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.occupants[rover.Position] = append(m.occupants[rover.Position], rover)
+}
+
+// Vacate
+// Removes the rover from its current position, reporting whether it was on the grid.
+func (m *Grid) Vacate(rover *Rover) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rovers := m.occupants[rover.Position]
+	for i, occupant := range rovers {
+		if occupant == rover {
+			rovers = append(rovers[:i], rovers[i+1:]...)
+			if len(rovers) == 0 {
+				delete(m.occupants, rover.Position)
+			} else {
+				m.occupants[rover.Position] = rovers
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// OccupantAt
+// Returns a rover occupying the position other than the given rover, or nil if there is none.
+func (m *Grid) OccupantAt(pos Position, rover *Rover) *Rover {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, occupant := range m.occupants[pos] {
+		if occupant != rover {
+			return occupant
+		}
+	}
+	return nil
+}
+
+func (m *Grid) Occupants() []*Rover {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var rovers []*Rover
+	for _, occupants := range m.occupants {
+		rovers = append(rovers, occupants...)
+	}
+	return rovers
 }
 
 func (m *Grid) PositionWithinBounds(pos Position) bool {
@@ -34,6 +378,16 @@ func (m *Grid) PositionWithinBounds(pos Position) bool {
 	return uint8(pos.X) <= m.XSize && pos.X > 0 && uint8(pos.Y) <= m.YSize
 }
 
+// Wrap
+// Returns the position with coordinates past an edge carried round to the opposite side of the grid.
+func (m *Grid) Wrap(pos Position) Position {
+	width, height := int(m.XSize)+1, int(m.YSize)+1
+	return Position{
+		X: int8((int(pos.X)%width + width) % width),
+		Y: int8((int(pos.Y)%height + height) % height),
+	}
+}
+
 func (m *Grid) PositionWithinBoundsXY(x, y int8) bool {
 	if x < 0 || y < 0 {
 		return false
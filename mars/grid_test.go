@@ -27,4 +27,174 @@ func TestGridBounds(t *testing.T) {
 				tt.desc, tt.x, tt.y, result, tt.expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestGridObstacles(t *testing.T) {
+	grid := NewGrid(3, 3)
+	grid.AddObstacle(NewPosition(1, 2))
+
+	if !grid.IsObstacle(NewPosition(1, 2)) {
+		t.Error("Expected (1,2) to be an obstacle")
+	}
+	if grid.IsObstacle(NewPosition(2, 1)) {
+		t.Error("Expected (2,1) not to be an obstacle")
+	}
+	if len(grid.Obstacles()) != 1 {
+		t.Errorf("Expected 1 obstacle, got %d", len(grid.Obstacles()))
+	}
+
+	for _, name := range []string{"skip", "stop"} {
+		policy, ok := ObstaclePolicyFromName(name)
+		if !ok || policy.String() != name {
+			t.Errorf("Expected policy %q to round-trip, got %q (ok=%v)", name, policy.String(), ok)
+		}
+	}
+	if _, ok := ObstaclePolicyFromName("bounce"); ok {
+		t.Error("Expected unknown obstacle policy to be rejected")
+	}
+}
+
+func TestGridOccupancy(t *testing.T) {
+	grid := NewGrid(3, 3)
+	first := NewRover(1, 1, North, grid)
+	second := NewRover(1, 1, East, grid)
+
+	grid.Occupy(first)
+	if grid.OccupantAt(NewPosition(1, 1), second) != first {
+		t.Error("Expected first rover to occupy (1,1)")
+	}
+	if grid.OccupantAt(NewPosition(1, 1), first) != nil {
+		t.Error("Expected a rover not to collide with itself")
+	}
+
+	grid.Occupy(second)
+	if !grid.Vacate(first) {
+		t.Error("Expected first rover to be vacated")
+	}
+	if grid.Vacate(first) {
+		t.Error("Expected vacating twice to report the rover as absent")
+	}
+	if grid.OccupantAt(NewPosition(1, 1), nil) != second {
+		t.Error("Expected second rover to remain at (1,1)")
+	}
+	if len(grid.Occupants()) != 1 {
+		t.Errorf("Expected 1 occupant, got %d", len(grid.Occupants()))
+	}
+}
+
+func TestGridScents(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     ScentMode
+		heading  Direction
+		expected bool
+	}{
+		{"exit mode protects the scented heading", ScentByExit, North, true},
+		{"exit mode leaves other headings open", ScentByExit, East, false},
+		{"position mode protects every heading", ScentByPosition, East, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := NewGrid(3, 3)
+			grid.ScentMode = tt.mode
+			grid.AddScent(NewPosition(3, 3), North)
+
+			if got := grid.IsExitScented(NewPosition(3, 3), tt.heading); got != tt.expected {
+				t.Errorf("Expected IsExitScented heading %s to be %v, got %v", tt.heading, tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Records the instruction that caused the loss", func(t *testing.T) {
+		grid := NewGrid(3, 3)
+		instruction := NewMovementInstruction(-2)
+		rover := NewRover(1, 1, North, grid)
+		rover.Instruct(instruction)
+
+		scents := grid.ExitScents()
+		if len(scents) != 1 {
+			t.Fatalf("Expected 1 scent, got %v", scents)
+		}
+		if scents[0].Position != NewPosition(1, 0) || scents[0].Direction != South || scents[0].Instruction != instruction {
+			t.Errorf("Expected scent at (1,0) heading S from B2, got %+v", scents[0])
+		}
+	})
+
+	for _, name := range []string{"exit", "position"} {
+		mode, ok := ScentModeFromName(name)
+		if !ok || mode.String() != name {
+			t.Errorf("Expected scent mode %q to round-trip, got %q (ok=%v)", name, mode.String(), ok)
+		}
+	}
+}
+
+func TestGridConcurrency(t *testing.T) {
+	grid := NewGrid(20, 20)
+	done := make(chan bool)
+	for x := range int8(20) {
+		go func() {
+			for y := range int8(20) {
+				grid.AddScent(NewPosition(x, y), North)
+				grid.IsExitScented(NewPosition(x, y), North)
+				rover := NewRover(x, y, North, grid)
+				grid.Occupy(rover)
+				grid.OccupantAt(NewPosition(x, y), nil)
+				grid.Vacate(rover)
+			}
+			done <- true
+		}()
+	}
+	for range 20 {
+		<-done
+	}
+
+	if len(grid.ExitScents()) != 400 || len(grid.Scents()) != 400 {
+		t.Errorf("Expected 400 scents, got %d", len(grid.ExitScents()))
+	}
+	if len(grid.Occupants()) != 0 {
+		t.Errorf("Expected every rover to have left, got %d", len(grid.Occupants()))
+	}
+}
+
+func TestGridRestore(t *testing.T) {
+	grid := NewGrid(5, 3)
+	recorder := &Recorder{}
+	grid.Subscribe(recorder)
+	snapshot := grid.Clone()
+
+	rover := NewRover(3, 3, North, grid)
+	grid.Occupy(rover)
+	rover.Move(1)
+
+	grid.Restore(snapshot)
+	if len(grid.ExitScents()) != 0 || len(grid.Occupants()) != 0 {
+		t.Errorf("Expected the scent and rover to be rolled back, got %v and %v", grid.ExitScents(), grid.Occupants())
+	}
+
+	seen := len(recorder.Events)
+	grid.Publish(MovedEvent{Rover: rover})
+	if len(recorder.Events) != seen+1 {
+		t.Error("Expected listeners to survive a restore")
+	}
+
+	grid.AddScent(NewPosition(0, 0), South)
+	if len(snapshot.ExitScents()) != 0 {
+		t.Error("Expected the snapshot not to share scents with the restored grid")
+	}
+}
+
+func TestGridClearScents(t *testing.T) {
+	grid := NewGrid(5, 3)
+	grid.AddScent(NewPosition(3, 3), North)
+	grid.AddScent(NewPosition(0, 0), West)
+
+	grid.ClearScents()
+	if len(grid.Scents()) != 0 || len(grid.ExitScents()) != 0 || grid.IsExitScented(NewPosition(3, 3), North) {
+		t.Errorf("Expected no scents, got %v", grid.ExitScents())
+	}
+
+	if !grid.LeaveScent(Scent{Position: NewPosition(3, 3), Direction: North}) {
+		t.Error("Expected a cleared exit to take a new scent")
+	}
+}
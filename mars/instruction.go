@@ -1,6 +1,9 @@
 package mars
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Instruction interface {
 	String() string
@@ -17,6 +20,19 @@ func (m MovementInstruction) String() string {
 
 func (m MovementInstruction) isInstruction() {}
 
+// Code
+// Returns the instruction as it would be typed, e.g. 'F', 'B' or 'F3'.
+func (m MovementInstruction) Code() string {
+	code, distance := "F", int(m.Distance)
+	if distance < 0 {
+		code, distance = "B", -distance
+	}
+	if distance == 1 {
+		return code
+	}
+	return fmt.Sprintf("%s%d", code, distance)
+}
+
 type RotationInstruction struct {
 	Orientation Rotation
 }
@@ -27,6 +43,68 @@ func (r RotationInstruction) String() string {
 
 func (r RotationInstruction) isInstruction() {}
 
+func (r RotationInstruction) Code() string {
+	return string(r.Orientation)
+}
+
+// MaxLoopIterations
+// The most times a loop instruction runs its body before giving up, so a condition that never holds cannot hang a
+// rover.
+const MaxLoopIterations = 100
+
+// ConditionalInstruction
+// Runs Then if the rover senses the condition ahead of it when the instruction is reached, and Else otherwise.
+type ConditionalInstruction struct {
+	Condition Condition
+	Then      []Instruction
+	Else      []Instruction
+}
+
+func (c ConditionalInstruction) String() string {
+	return fmt.Sprintf("If (%s)", c.Condition)
+}
+
+func (c ConditionalInstruction) isInstruction() {}
+
+// Code
+// Returns the instruction as it would be typed, e.g. 'IF BLOCKED (R) ELSE (F)'.
+func (c ConditionalInstruction) Code() string {
+	code := fmt.Sprintf("IF %s (%s)", strings.ToUpper(c.Condition.String()), ProgramCode(c.Then))
+	if len(c.Else) > 0 {
+		code += fmt.Sprintf(" ELSE (%s)", ProgramCode(c.Else))
+	}
+	return code
+}
+
+// Branch
+// Returns the instructions the rover would run for the condition it senses now.
+func (c ConditionalInstruction) Branch(rover *Rover) []Instruction {
+	if rover.Sense(c.Condition) {
+		return c.Then
+	}
+	return c.Else
+}
+
+// LoopInstruction
+// Runs Body again and again until the rover senses the condition ahead of it, checking before each pass, so the body
+// never runs if the condition already holds. Gives up after MaxLoopIterations passes.
+type LoopInstruction struct {
+	Until Condition
+	Body  []Instruction
+}
+
+func (l LoopInstruction) String() string {
+	return fmt.Sprintf("Until (%s)", l.Until)
+}
+
+func (l LoopInstruction) isInstruction() {}
+
+// Code
+// Returns the instruction as it would be typed, e.g. 'UNTIL BLOCKED (F)'.
+func (l LoopInstruction) Code() string {
+	return fmt.Sprintf("UNTIL %s (%s)", strings.ToUpper(l.Until.String()), ProgramCode(l.Body))
+}
+
 func NewMovementInstruction(direction int8) *MovementInstruction {
 	return &MovementInstruction{
 		Distance: direction,
@@ -38,3 +116,38 @@ func NewOrientationInstruction(orientation Rotation) *RotationInstruction {
 		Orientation: orientation,
 	}
 }
+
+// InstructionCode
+// Returns the code an instruction would be typed as, falling back to its description for instructions without one.
+func InstructionCode(instruction Instruction) string {
+	if coded, ok := instruction.(interface{ Code() string }); ok {
+		return coded.Code()
+	}
+	return instruction.String()
+}
+
+// ProgramCode
+// Returns the instructions as they would be typed, one code after another.
+func ProgramCode(instructions []Instruction) string {
+	var code strings.Builder
+	for _, instruction := range instructions {
+		code.WriteString(InstructionCode(instruction))
+	}
+	return code.String()
+}
+
+// InstructionCount
+// Counts the instructions including those inside conditional and loop instructions, each of which also counts as one.
+func InstructionCount(instructions []Instruction) int {
+	count := 0
+	for _, instruction := range instructions {
+		count++
+		switch instruction := instruction.(type) {
+		case *ConditionalInstruction:
+			count += InstructionCount(instruction.Then) + InstructionCount(instruction.Else)
+		case *LoopInstruction:
+			count += InstructionCount(instruction.Body)
+		}
+	}
+	return count
+}
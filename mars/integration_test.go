@@ -1,17 +1,18 @@
 package mars
 
 import (
-	"bufio"
-	"marster-bot/output"
-	"strings"
+	"errors"
 	"testing"
 )
 
+func isLost(err error) bool {
+	var lostErr *LostError
+	return errors.As(err, &lostErr)
+}
+
 func TestRoverIntegration(t *testing.T) {
 	t.Run("Happy path - single rover moves correctly", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		grid := NewGrid(5, 5)
 		rover := NewRover(1, 2, North, grid)
 
@@ -30,7 +31,7 @@ func TestRoverIntegration(t *testing.T) {
 
 		// Execute instructions
 		for _, inst := range instructions {
-			err := rover.Instruct(console, inst)
+			err := rover.Instruct(inst)
 			if err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
@@ -47,8 +48,6 @@ func TestRoverIntegration(t *testing.T) {
 
 	t.Run("Rover falls off and leaves scent", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		grid := NewGrid(5, 5)
 		rover := NewRover(3, 3, North, grid)
 
@@ -62,7 +61,7 @@ func TestRoverIntegration(t *testing.T) {
 		// Execute instructions
 		var fallOffError error
 		for _, inst := range instructions {
-			err := rover.Instruct(console, inst)
+			err := rover.Instruct(inst)
 			if err != nil {
 				fallOffError = err
 				break
@@ -73,8 +72,11 @@ func TestRoverIntegration(t *testing.T) {
 		if fallOffError == nil {
 			t.Error("Expected rover to fall off grid")
 		}
-		if !strings.Contains(fallOffError.Error(), "fell off the grid") {
+		var lostErr *LostError
+		if !errors.As(fallOffError, &lostErr) {
 			t.Errorf("Expected fall off error, got: %v", fallOffError)
+		} else if !lostErr.Position.Equals(NewPosition(3, 5)) || !lostErr.Direction.Equals(North) {
+			t.Errorf("Expected rover lost at 3 5 N, got %d %d %s", lostErr.Position.X, lostErr.Position.Y, lostErr.Direction)
 		}
 
 		// Verify scent was left at the edge position
@@ -91,8 +93,6 @@ func TestRoverIntegration(t *testing.T) {
 
 	t.Run("Second rover ignores instruction at scented position", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		grid := NewGrid(5, 5)
 		
 		// First rover falls off at (3,5) facing North
@@ -104,7 +104,7 @@ func TestRoverIntegration(t *testing.T) {
 		}
 		
 		for _, inst := range instructions1 {
-			rover1.Instruct(console, inst)
+			rover1.Instruct(inst)
 		}
 
 		// Verify scent exists
@@ -116,7 +116,7 @@ func TestRoverIntegration(t *testing.T) {
 		rover2 := NewRover(3, 5, North, grid)
 		
 		// Try to move forward (which would fall off)
-		err := rover2.Instruct(console, NewMovementInstruction(1))
+		err := rover2.Instruct(NewMovementInstruction(1))
 		
 		// Should not error (instruction ignored due to scent)
 		if err != nil {
@@ -129,12 +129,12 @@ func TestRoverIntegration(t *testing.T) {
 		}
 
 		// Rover should be able to turn and move elsewhere
-		err = rover2.Instruct(console, NewOrientationInstruction(Right)) // Face East
+		err = rover2.Instruct(NewOrientationInstruction(Right)) // Face East
 		if err != nil {
 			t.Errorf("Unexpected error turning: %v", err)
 		}
 		
-		err = rover2.Instruct(console, NewMovementInstruction(1)) // Move to (4,5)
+		err = rover2.Instruct(NewMovementInstruction(1)) // Move to (4,5)
 		if err != nil {
 			t.Errorf("Unexpected error moving east: %v", err)
 		}
@@ -146,17 +146,15 @@ func TestRoverIntegration(t *testing.T) {
 
 	t.Run("Multiple scents at different positions", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		grid := NewGrid(3, 3)
 		
 		// First rover falls off North edge
 		rover1 := NewRover(1, 3, North, grid)
-		rover1.Instruct(console, NewMovementInstruction(1))
+		rover1.Instruct(NewMovementInstruction(1))
 		
 		// Second rover falls off East edge
 		rover2 := NewRover(3, 1, East, grid)
-		rover2.Instruct(console, NewMovementInstruction(1))
+		rover2.Instruct(NewMovementInstruction(1))
 		
 		// Verify both scents exist
 		if !grid.IsScented(Position{X: 1, Y: 3}) {
@@ -168,7 +166,7 @@ func TestRoverIntegration(t *testing.T) {
 
 		// Third rover should be protected at both scented positions
 		rover3 := NewRover(1, 3, North, grid)
-		err := rover3.Instruct(console, NewMovementInstruction(1))
+		err := rover3.Instruct(NewMovementInstruction(1))
 		if err != nil {
 			t.Errorf("Expected no error at scented (1,3), got: %v", err)
 		}
@@ -176,7 +174,7 @@ func TestRoverIntegration(t *testing.T) {
 		// Move to other scented position
 		rover3.Position = Position{X: 3, Y: 1}
 		rover3.Direction = East
-		err = rover3.Instruct(console, NewMovementInstruction(1))
+		err = rover3.Instruct(NewMovementInstruction(1))
 		if err != nil {
 			t.Errorf("Expected no error at scented (3,1), got: %v", err)
 		}
@@ -184,74 +182,92 @@ func TestRoverIntegration(t *testing.T) {
 
 	t.Run("Rover at corner can fall off in two directions", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		
 		// Test North direction with fresh grid
 		grid1 := NewGrid(3, 3)
 		rover1 := NewRover(3, 3, North, grid1)
 		
 		// Try to move North (should fall off)
-		err := rover1.Instruct(console, NewMovementInstruction(1))
-		if err == nil || !strings.Contains(err.Error(), "fell off") {
+		err := rover1.Instruct(NewMovementInstruction(1))
+		if !isLost(err) {
 			t.Error("Expected rover to fall off moving North from corner")
 		}
 		
 		// Test East direction with fresh grid
 		grid2 := NewGrid(3, 3)
 		rover2 := NewRover(3, 3, East, grid2)
-		err = rover2.Instruct(console, NewMovementInstruction(1))
-		if err == nil || !strings.Contains(err.Error(), "fell off") {
+		err = rover2.Instruct(NewMovementInstruction(1))
+		if !isLost(err) {
 			t.Errorf("Expected rover to fall off moving East from corner, got err=%v, pos=(%d,%d)", err, rover2.Position.X, rover2.Position.Y)
 		}
 		
-		// Verify scent protects from both directions on same grid
+		// Verify scent protects from both directions on same grid when scents are position-only
 		grid3 := NewGrid(3, 3)
+		grid3.ScentMode = ScentByPosition
 		rover3a := NewRover(3, 3, North, grid3)
-		rover3a.Instruct(console, NewMovementInstruction(1)) // Falls off, leaves scent
+		rover3a.Instruct(NewMovementInstruction(1)) // Falls off, leaves scent
 		
 		rover3b := NewRover(3, 3, North, grid3)
-		err = rover3b.Instruct(console, NewMovementInstruction(1))
+		err = rover3b.Instruct(NewMovementInstruction(1))
 		if err != nil {
 			t.Errorf("Expected no error at scented corner position facing North: %v", err)
 		}
 		
 		rover3b.Direction = East
-		err = rover3b.Instruct(console, NewMovementInstruction(1))
+		err = rover3b.Instruct(NewMovementInstruction(1))
 		if err != nil {
 			t.Errorf("Expected no error at scented corner position facing East: %v", err)
 		}
+
+		// By default the scent only protects the edge the first rover fell off
+		grid4 := NewGrid(3, 3)
+		rover4a := NewRover(3, 3, North, grid4)
+		rover4a.Instruct(NewMovementInstruction(1))
+
+		rover4b := NewRover(3, 3, North, grid4)
+		err = rover4b.Instruct(NewMovementInstruction(1))
+		if err != nil {
+			t.Errorf("Expected no error at scented corner exit facing North: %v", err)
+		}
+
+		rover4b.Direction = East
+		err = rover4b.Instruct(NewMovementInstruction(1))
+		if !isLost(err) {
+			t.Errorf("Expected rover to fall off the unscented East edge of the corner, got: %v", err)
+		}
+		if !grid4.IsExitScented(Position{X: 3, Y: 3}, East) {
+			t.Error("Expected the East exit of the corner to be scented after the second fall")
+		}
 	})
 
 	t.Run("Rover at origin boundary conditions", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		
 		// Test South direction with fresh grid
 		grid1 := NewGrid(5, 5)
 		rover1 := NewRover(0, 0, South, grid1)
-		err := rover1.Instruct(console, NewMovementInstruction(1))
+		err := rover1.Instruct(NewMovementInstruction(1))
 		
 		// Should fall off
-		if err == nil || !strings.Contains(err.Error(), "fell off") {
+		if !isLost(err) {
 			t.Error("Expected rover to fall off at origin moving South")
 		}
 		
 		// Test West direction with fresh grid
 		grid2 := NewGrid(5, 5)
 		rover2 := NewRover(0, 0, West, grid2)
-		err = rover2.Instruct(console, NewMovementInstruction(1))
+		err = rover2.Instruct(NewMovementInstruction(1))
 		
 		// Should fall off
-		if err == nil || !strings.Contains(err.Error(), "fell off") {
+		if !isLost(err) {
 			t.Error("Expected rover to fall off at origin moving West")
 		}
 		
-		// Verify scents protect at origin on same grid
+		// Verify scents protect at origin on same grid when scents are position-only
 		grid3 := NewGrid(5, 5)
+		grid3.ScentMode = ScentByPosition
 		rover3a := NewRover(0, 0, South, grid3)
-		rover3a.Instruct(console, NewMovementInstruction(1)) // Falls off, leaves scent
+		rover3a.Instruct(NewMovementInstruction(1)) // Falls off, leaves scent
 		
 		if !grid3.IsScented(Position{X: 0, Y: 0}) {
 			t.Error("Expected scent at origin after fall")
@@ -259,7 +275,7 @@ func TestRoverIntegration(t *testing.T) {
 		
 		// New rover at origin should be protected
 		rover3b := NewRover(0, 0, West, grid3)
-		err = rover3b.Instruct(console, NewMovementInstruction(1))
+		err = rover3b.Instruct(NewMovementInstruction(1))
 		if err != nil {
 			t.Errorf("Expected no error at scented origin, got: %v", err)
 		}
@@ -267,8 +283,6 @@ func TestRoverIntegration(t *testing.T) {
 
 	t.Run("Complex path with rotations and movements", func(t *testing.T) {
 		// Setup
-		reader := bufio.NewReader(strings.NewReader(""))
-		console := output.NewConsole(*reader, false)
 		grid := NewGrid(5, 5)
 		rover := NewRover(1, 1, East, grid)
 
@@ -289,7 +303,7 @@ func TestRoverIntegration(t *testing.T) {
 
 		var lastErr error
 		for _, inst := range instructions {
-			err := rover.Instruct(console, inst)
+			err := rover.Instruct(inst)
 			if err != nil {
 				lastErr = err
 				break
@@ -297,7 +311,7 @@ func TestRoverIntegration(t *testing.T) {
 		}
 
 		// Should have fallen off at East boundary
-		if lastErr == nil || !strings.Contains(lastErr.Error(), "fell off") {
+		if !isLost(lastErr) {
 			t.Error("Expected rover to fall off East boundary")
 		}
 
@@ -1,6 +1,9 @@
 package mars
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 type Position struct {
 	X int8
@@ -23,8 +26,28 @@ func (p *Position) Copy() Position {
 	return Position{X: p.X, Y: p.Y}
 }
 
+// Step
+// Returns the neighbouring position one cell away in the given direction.
+func (p *Position) Step(direction Direction) Position {
+	switch direction {
+	case North:
+		return Position{X: p.X, Y: p.Y + 1}
+	case East:
+		return Position{X: p.X + 1, Y: p.Y}
+	case South:
+		return Position{X: p.X, Y: p.Y - 1}
+	case West:
+		return Position{X: p.X - 1, Y: p.Y}
+	default:
+		return p.Copy()
+	}
+}
+
+// PositionSet
+// A set of positions that is safe for concurrent use.
 type PositionSet struct {
-	m map[string]Position
+	mu sync.RWMutex
+	m  map[string]Position
 }
 
 func NewPositionSet() *PositionSet {
@@ -39,21 +62,29 @@ func (s *PositionSet) getKey(pos Position) string {
 
 func (s *PositionSet) Add(pos Position) {
 	key := s.getKey(pos)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.m[key] = pos
 }
 
 func (s *PositionSet) Has(pos Position) bool {
 	key := s.getKey(pos)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	_, ok := s.m[key]
 	return ok
 }
 
 func (s *PositionSet) Del(pos Position) {
 	key := s.getKey(pos)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	delete(s.m, key)
 }
 
 func (s *PositionSet) Keys() []Position {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	positions := make([]Position, 0, len(s.m))
 	for _, pos := range s.m {
 		positions = append(positions, pos)
@@ -62,5 +93,27 @@ func (s *PositionSet) Keys() []Position {
 }
 
 func (s *PositionSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return len(s.m)
 }
+
+// Clear
+// Removes every position from the set.
+func (s *PositionSet) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.m)
+}
+
+// Clone
+// Returns an independent copy of the set.
+func (s *PositionSet) Clone() *PositionSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	clone := NewPositionSet()
+	for key, pos := range s.m {
+		clone.m[key] = pos
+	}
+	return clone
+}
@@ -0,0 +1,252 @@
+package mars
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// InstructionBase
+// Embedded by instructions declared outside this package so they satisfy Instruction.
+type InstructionBase struct{}
+
+func (InstructionBase) isInstruction() {}
+
+// InstructionKind
+// Describes a kind of instruction to the instruction language and to rovers: the code letters that start it, how
+// the characters after a code are parsed, how it is described in help text, and how a rover executes it. Kinds
+// without codes, like conditionals, have syntax of their own in the language but are still executed through the
+// registry. Instructions should implement Code so they can be written back out, e.g. by the session's ':save'.
+type InstructionKind struct {
+	Name        string
+	Codes       []rune
+	Usage       string // short help for the prompt, e.g. 'F=Forward, B=Backward'
+	Description string
+	Argument    string // what Parse reads after the code, e.g. 'distance', for error messages
+	// Parse builds an instruction from its code and the characters after it, returning how many of them it used,
+	// or on error how many of them are at fault.
+	Parse func(code rune, args []rune) (Instruction, int, error)
+	// Instruction is an instruction of the type the kind executes, e.g. &MovementInstruction{}.
+	Instruction Instruction
+	Execute     func(rover *Rover, instruction Instruction) error
+}
+
+// instructionRegistry
+// Every registered kind in registration order, indexed by code and by instruction type. Safe for concurrent use.
+type instructionRegistry struct {
+	mu    sync.RWMutex
+	kinds []*InstructionKind
+	codes map[rune]*InstructionKind
+	types map[reflect.Type]*InstructionKind
+}
+
+var registry = &instructionRegistry{
+	codes: make(map[rune]*InstructionKind),
+	types: make(map[reflect.Type]*InstructionKind),
+}
+
+// RegisterInstruction
+// Adds a kind of instruction, so its codes are understood by the instruction language and rovers can execute it.
+// Codes must be uppercase letters, and neither a code, the name nor the instruction type may already be registered.
+func RegisterInstruction(kind InstructionKind) error {
+	if kind.Name == "" {
+		return fmt.Errorf("instruction kind needs a name")
+	}
+	if kind.Instruction == nil || kind.Execute == nil {
+		return fmt.Errorf("instruction kind '%s' needs an instruction and an executor", kind.Name)
+	}
+	if len(kind.Codes) > 0 && kind.Parse == nil {
+		return fmt.Errorf("instruction kind '%s' has codes but no parser", kind.Name)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for _, existing := range registry.kinds {
+		if existing.Name == kind.Name {
+			return fmt.Errorf("instruction kind '%s' is already registered", kind.Name)
+		}
+	}
+	for _, code := range kind.Codes {
+		if !unicode.IsUpper(code) {
+			return fmt.Errorf("instruction kind '%s': code '%c' must be an uppercase letter", kind.Name, code)
+		}
+		if existing, ok := registry.codes[code]; ok {
+			return fmt.Errorf("instruction kind '%s': code '%c' is already used by '%s'", kind.Name, code, existing.Name)
+		}
+	}
+	instructionType := reflect.TypeOf(kind.Instruction)
+	if existing, ok := registry.types[instructionType]; ok {
+		return fmt.Errorf("instruction kind '%s': %v is already executed by '%s'", kind.Name, instructionType, existing.Name)
+	}
+
+	registered := &kind
+	registry.kinds = append(registry.kinds, registered)
+	for _, code := range kind.Codes {
+		registry.codes[code] = registered
+	}
+	registry.types[instructionType] = registered
+	return nil
+}
+
+// UnregisterInstruction
+// Removes the kind with the given name, reporting whether it was registered.
+func UnregisterInstruction(name string) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	for i, kind := range registry.kinds {
+		if kind.Name != name {
+			continue
+		}
+		registry.kinds = append(registry.kinds[:i], registry.kinds[i+1:]...)
+		for _, code := range kind.Codes {
+			delete(registry.codes, code)
+		}
+		delete(registry.types, reflect.TypeOf(kind.Instruction))
+		return true
+	}
+	return false
+}
+
+// InstructionKindForCode
+// Returns the kind started by the code letter.
+func InstructionKindForCode(code rune) (InstructionKind, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	kind, ok := registry.codes[code]
+	if !ok {
+		return InstructionKind{}, false
+	}
+	return *kind, true
+}
+
+// InstructionKinds
+// Returns every registered kind in the order they were registered.
+func InstructionKinds() []InstructionKind {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	kinds := make([]InstructionKind, 0, len(registry.kinds))
+	for _, kind := range registry.kinds {
+		kinds = append(kinds, *kind)
+	}
+	return kinds
+}
+
+// InstructionCodes
+// Returns every registered code letter, e.g. 'R, L, T, F, B'.
+func InstructionCodes() string {
+	var codes []string
+	for _, kind := range InstructionKinds() {
+		for _, code := range kind.Codes {
+			codes = append(codes, string(code))
+		}
+	}
+	return strings.Join(codes, ", ")
+}
+
+// InstructionHelp
+// Returns the short help of every kind that has one, e.g. 'R=Right, L=Left, T=Turn around, F=Forward, B=Backward'.
+func InstructionHelp() string {
+	var usages []string
+	for _, kind := range InstructionKinds() {
+		if kind.Usage != "" {
+			usages = append(usages, kind.Usage)
+		}
+	}
+	return strings.Join(usages, ", ")
+}
+
+// Execute
+// Carries out the instruction with the executor registered for its type, without publishing an InstructedEvent.
+func (r *Rover) Execute(instruction Instruction) error {
+	registry.mu.RLock()
+	kind, ok := registry.types[reflect.TypeOf(instruction)]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownInstruction, instruction)
+	}
+	return kind.Execute(r, instruction)
+}
+
+func init() {
+	builtins := []InstructionKind{
+		{
+			Name:        "rotate",
+			Codes:       []rune{rune(Right), rune(Left), rune(Around)},
+			Usage:       "R=Right, L=Left, T=Turn around",
+			Description: "Turn 90° right, 90° left, or 180° to face the other way",
+			Parse: func(code rune, args []rune) (Instruction, int, error) {
+				return NewOrientationInstruction(Rotation(code)), 0, nil
+			},
+			Instruction: &RotationInstruction{},
+			Execute: func(rover *Rover, instruction Instruction) error {
+				return rover.Rotate(instruction.(*RotationInstruction).Orientation)
+			},
+		},
+		{
+			Name:        "move",
+			Codes:       []rune{'F', 'B'},
+			Usage:       "F=Forward, B=Backward",
+			Description: "Move forward, or backward keeping the heading, by an optional distance, e.g. F3",
+			Argument:    "distance",
+			Parse:       parseMovement,
+			Instruction: &MovementInstruction{},
+			Execute: func(rover *Rover, instruction Instruction) error {
+				return rover.Move(instruction.(*MovementInstruction).Distance)
+			},
+		},
+		{
+			Name:        "if",
+			Description: "IF condition (...) ELSE (...) runs the first block if the rover senses the condition ahead",
+			Instruction: &ConditionalInstruction{},
+			Execute: func(rover *Rover, instruction Instruction) error {
+				return rover.instructAll(instruction.(*ConditionalInstruction).Branch(rover))
+			},
+		},
+		{
+			Name:        "until",
+			Description: "UNTIL condition (...) runs the block until the rover senses the condition ahead",
+			Instruction: &LoopInstruction{},
+			Execute: func(rover *Rover, instruction Instruction) error {
+				return rover.loop(instruction.(*LoopInstruction))
+			},
+		},
+	}
+
+	for _, kind := range builtins {
+		if err := RegisterInstruction(kind); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// parseMovement
+// Reads the optional distance after a movement code, 1 when there is none. Backward moves have negative distances.
+func parseMovement(code rune, args []rune) (Instruction, int, error) {
+	digits := 0
+	for digits < len(args) && unicode.IsDigit(args[digits]) {
+		digits++
+	}
+
+	distance := int64(1)
+	if digits > 0 {
+		var err error
+		distance, err = strconv.ParseInt(string(args[:digits]), 10, 8)
+		if err != nil || distance == 0 {
+			return nil, digits, fmt.Errorf("distance must be between 1 and %d", math.MaxInt8)
+		}
+	}
+
+	if code == 'B' {
+		distance = -distance
+	}
+	return NewMovementInstruction(int8(distance)), digits, nil
+}
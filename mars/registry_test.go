@@ -0,0 +1,140 @@
+package mars
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// jumpInstruction
+// Moves the rover straight to the cell a number of spaces ahead, without stepping through the cells between.
+type jumpInstruction struct {
+	InstructionBase
+	Distance int8
+}
+
+func (j *jumpInstruction) String() string {
+	return fmt.Sprintf("Jump (%d)", j.Distance)
+}
+
+func (j *jumpInstruction) Code() string {
+	return fmt.Sprintf("J%d", j.Distance)
+}
+
+func jumpKind() InstructionKind {
+	return InstructionKind{
+		Name:        "jump",
+		Codes:       []rune{'J'},
+		Usage:       "J=Jump",
+		Description: "Jump a number of spaces ahead",
+		Argument:    "distance",
+		Parse: func(code rune, args []rune) (Instruction, int, error) {
+			if len(args) == 0 || args[0] < '1' || args[0] > '9' {
+				return nil, 0, fmt.Errorf("a jump needs a distance between 1 and 9")
+			}
+			return &jumpInstruction{Distance: int8(args[0] - '0')}, 1, nil
+		},
+		Instruction: &jumpInstruction{},
+		Execute: func(rover *Rover, instruction Instruction) error {
+			to := rover.Position
+			for range instruction.(*jumpInstruction).Distance {
+				to = to.Step(rover.Direction)
+			}
+			if !rover.Grid.PositionWithinBoundsXY(to.X, to.Y) {
+				return rover.OnGridExit()
+			}
+			rover.Advance(to)
+			return nil
+		},
+	}
+}
+
+func TestRegisterInstruction(t *testing.T) {
+	if err := RegisterInstruction(jumpKind()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer UnregisterInstruction("jump")
+
+	t.Run("Looks up codes", func(t *testing.T) {
+		kind, ok := InstructionKindForCode('J')
+		if !ok || kind.Name != "jump" {
+			t.Fatalf("Expected the jump kind for 'J', got %+v", kind)
+		}
+		instruction, used, err := kind.Parse('J', []rune("3F"))
+		if err != nil || used != 1 || InstructionCode(instruction) != "J3" {
+			t.Errorf("Expected J3 using 1 character, got %v using %d (%v)", instruction, used, err)
+		}
+	})
+
+	t.Run("Generates help", func(t *testing.T) {
+		expected := "R=Right, L=Left, T=Turn around, F=Forward, B=Backward, J=Jump"
+		if help := InstructionHelp(); help != expected {
+			t.Errorf("Expected help %q, got %q", expected, help)
+		}
+		if codes := InstructionCodes(); codes != "R, L, T, F, B, J" {
+			t.Errorf("Expected codes 'R, L, T, F, B, J', got %q", codes)
+		}
+	})
+
+	t.Run("Rover executes registered kinds", func(t *testing.T) {
+		grid := NewGrid(5, 5)
+		rover := NewRover(1, 1, North, grid)
+		if err := rover.Instruct(&jumpInstruction{Distance: 3}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if rover.Position != (Position{X: 1, Y: 4}) {
+			t.Errorf("Expected rover at (1, 4), got %v", rover.Position)
+		}
+
+		var lost *LostError
+		if err := rover.Instruct(&jumpInstruction{Distance: 2}); !errors.As(err, &lost) {
+			t.Fatalf("Expected LostError, got %v", err)
+		}
+		if scent := grid.ExitScents()[0]; InstructionCode(scent.Instruction) != "J2" {
+			t.Errorf("Expected the scent to record J2, got %v", scent.Instruction)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		kind   func(kind *InstructionKind)
+		errMsg string
+	}{
+		{"Missing name", func(kind *InstructionKind) { kind.Name = "" }, "needs a name"},
+		{"Missing executor", func(kind *InstructionKind) { kind.Execute = nil }, "needs an instruction and an executor"},
+		{"Missing parser", func(kind *InstructionKind) { kind.Parse = nil }, "has codes but no parser"},
+		{"Duplicate name", func(kind *InstructionKind) { kind.Codes = []rune{'K'} }, "'jump' is already registered"},
+		{"Lowercase code", func(kind *InstructionKind) { kind.Name = "hop"; kind.Codes = []rune{'h'} }, "code 'h' must be an uppercase letter"},
+		{"Code taken", func(kind *InstructionKind) { kind.Name = "fly"; kind.Codes = []rune{'F'} }, "code 'F' is already used by 'move'"},
+		{"Type taken", func(kind *InstructionKind) { kind.Name = "leap"; kind.Codes = []rune{'K'} }, "is already executed by 'jump'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind := jumpKind()
+			tt.kind(&kind)
+			err := RegisterInstruction(kind)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+
+	t.Run("Unregisters", func(t *testing.T) {
+		if !UnregisterInstruction("jump") {
+			t.Fatal("Expected jump to be unregistered")
+		}
+		if _, ok := InstructionKindForCode('J'); ok {
+			t.Error("Expected 'J' to no longer be a code")
+		}
+		if err := NewRover(1, 1, North, NewGrid(5, 5)).Instruct(&jumpInstruction{Distance: 1}); !errors.Is(err, ErrUnknownInstruction) {
+			t.Errorf("Expected ErrUnknownInstruction, got %v", err)
+		}
+		if UnregisterInstruction("jump") {
+			t.Error("Expected a second unregister to report false")
+		}
+	})
+}
@@ -1,14 +1,15 @@
 package mars
 
-import (
-	"fmt"
-	"marster-bot/output"
-)
-
 type Rover struct {
-	Position  Position
-	Direction Direction
-	Grid      *Grid
+	ID         int
+	Position   Position
+	Direction  Direction
+	Grid       *Grid
+	Lost       bool
+	Collisions []Collision
+	Trail      []Position
+	// instruction is the instruction being executed, remembered so a scent can record what caused a loss.
+	instruction Instruction
 }
 
 func NewRover(x, y int8, startingDirection Direction, grid *Grid) *Rover {
@@ -19,78 +20,195 @@ func NewRover(x, y int8, startingDirection Direction, grid *Grid) *Rover {
 		},
 		Direction: startingDirection,
 		Grid:      grid,
+		Trail:     []Position{{X: x, Y: y}},
+	}
+}
+
+// StepKind
+// What a single step would do to a rover, before other rovers are taken into account.
+type StepKind uint8
+
+const (
+	// StepMove moves the rover to the neighbouring cell.
+	StepMove StepKind = iota
+	// StepWrap moves the rover across the edge to the opposite side of the grid.
+	StepWrap
+	// StepFall loses the rover off the edge.
+	StepFall
+	// StepScented ignores a move off the edge because the exit is scented.
+	StepScented
+	// StepClamp keeps the rover against the wall at the edge.
+	StepClamp
+	// StepBounce turns the rover around at the edge.
+	StepBounce
+	// StepObstacle is blocked by an obstacle in the neighbouring cell.
+	StepObstacle
+)
+
+// Step
+// The outcome of a single step in a heading, and the cell it leads to for moves and obstacles.
+type Step struct {
+	Kind    StepKind
+	Heading Direction
+	To      Position
+}
+
+// PlanStep
+// Works out what a single step in the given heading would do under the grid's edge policy, scents and obstacles,
+// without changing anything. Other rovers are not taken into account.
+func (r *Rover) PlanStep(heading Direction) Step {
+	step := Step{Kind: StepMove, Heading: heading, To: r.Position.Step(heading)}
+	if !r.Grid.PositionWithinBoundsXY(step.To.X, step.To.Y) {
+		switch r.Grid.EdgePolicy {
+		case EdgeClamp:
+			return Step{Kind: StepClamp, Heading: heading, To: r.Position}
+		case EdgeBounce:
+			return Step{Kind: StepBounce, Heading: heading, To: r.Position}
+		case EdgeWrap:
+			step = Step{Kind: StepWrap, Heading: heading, To: r.Grid.Wrap(step.To)}
+		default:
+			if r.Grid.IsExitScented(r.Position, heading) {
+				return Step{Kind: StepScented, Heading: heading, To: r.Position}
+			}
+			return Step{Kind: StepFall, Heading: heading, To: r.Position}
+		}
+	}
+
+	if r.Grid.IsObstacle(step.To) {
+		return Step{Kind: StepObstacle, Heading: heading, To: step.To}
+	}
+
+	return step
+}
+
+// Report
+// Publishes the event for a planned step that reached the edge, was ignored because of a scent, or was blocked by
+// an obstacle. Plain moves and falls publish their own events when they happen.
+func (r *Rover) Report(step Step) {
+	switch step.Kind {
+	case StepClamp:
+		r.Grid.Publish(ReachedEdgeEvent{Rover: r, Position: r.Position, Direction: step.Heading, Policy: EdgeClamp})
+	case StepBounce:
+		r.Grid.Publish(ReachedEdgeEvent{Rover: r, Position: r.Position, Direction: step.Heading, Policy: EdgeBounce})
+	case StepWrap:
+		r.Grid.Publish(ReachedEdgeEvent{Rover: r, Position: r.Position, Direction: step.Heading, Policy: EdgeWrap})
+	case StepScented:
+		r.Grid.Publish(BlockedByScentEvent{Rover: r, Position: r.Position, Direction: step.Heading})
+	case StepObstacle:
+		r.Grid.Publish(BlockedByObstacleEvent{Rover: r, Obstacle: step.To})
 	}
 }
 
 // Move
 // Moves the rover by the specified distance with the direction (forwards or backwards) dictated by the sign.
-func (r *Rover) Move(console *output.Console, distance int8) error {
-	switch r.Direction {
-	case North:
-		if !r.Grid.PositionWithinBoundsXY(r.Position.X, r.Position.Y+1) {
-			if !r.CurrentPositionIsScented() {
-				return r.OnGridExit()
-			}
+// The rover steps one cell at a time, so every intermediate cell is bounds-checked and a rover that falls off
+// is lost from the last cell it safely reached. A step off the edge is handled according to the grid's EdgePolicy,
+// and a step into an obstacle or another rover according to its ObstaclePolicy and CollisionPolicy.
+func (r *Rover) Move(distance int8) error {
+	heading := r.Direction
+	steps := int(distance)
+	if steps < 0 {
+		heading = heading.Rotate(Around)
+		steps = -steps
+	}
 
-			return nil
-		}
-		r.Position.Y += distance
-	case East:
-		if !r.Grid.PositionWithinBoundsXY(r.Position.X+1, r.Position.Y) {
-			if !r.CurrentPositionIsScented() {
-				return r.OnGridExit()
-			}
+	for range steps {
+		step := r.PlanStep(heading)
+		r.Report(step)
 
+		switch step.Kind {
+		case StepFall:
+			return r.exit(heading)
+		case StepScented, StepClamp:
 			return nil
-		}
-		r.Position.X += distance
-	case South:
-		if !r.Grid.PositionWithinBoundsXY(r.Position.X, r.Position.Y-1) {
-			if !r.CurrentPositionIsScented() {
-				return r.OnGridExit()
+		case StepBounce:
+			heading = heading.Rotate(Around)
+			r.Rotate(Around)
+			continue
+		case StepObstacle:
+			if r.Grid.ObstaclePolicy == ObstacleStop {
+				return &ObstacleError{Position: step.To}
 			}
-
 			return nil
 		}
-		r.Position.Y -= distance
-	case West:
-		if !r.Grid.PositionWithinBoundsXY(r.Position.X-1, r.Position.Y) {
-			if !r.CurrentPositionIsScented() {
-				return r.OnGridExit()
-			}
 
-			return nil
+		if other := r.Grid.OccupantAt(step.To, r); other != nil {
+			r.Grid.Publish(CollidedEvent{Rover: r, Other: other, Position: step.To})
+			collision := Collision{Position: step.To, Other: other.ID}
+
+			switch r.Grid.CollisionPolicy {
+			case CollisionAbort:
+				return &CollisionError{Position: step.To, Rover: r.ID, Other: other.ID}
+			case CollisionBlock:
+				r.Collisions = append(r.Collisions, collision)
+				return nil
+			case CollisionAllow:
+				r.Collisions = append(r.Collisions, collision)
+			}
 		}
-		r.Position.X -= distance
-	}
 
-	console.Debug("Rover moved to (%d, %d)", r.Position.X, r.Position.Y)
+		r.Advance(step.To)
+	}
 
 	return nil
 }
 
+// Advance
+// Moves the rover to the given cell, keeping its trail and the grid's occupancy in step.
+func (r *Rover) Advance(to Position) {
+	from := r.Position
+	placed := r.Grid.Vacate(r)
+	r.Position = to
+	r.Trail = append(r.Trail, to)
+	if placed {
+		r.Grid.Occupy(r)
+	}
+
+	r.Grid.Publish(MovedEvent{Rover: r, From: from, To: to})
+}
+
 func (r *Rover) Rotate(orientation Rotation) error {
+	from := r.Direction
 	r.Direction = r.Direction.Rotate(orientation)
+	r.Grid.Publish(RotatedEvent{Rover: r, From: from, To: r.Direction})
 	return nil
 }
 
-func (r *Rover) Instruct(console *output.Console, instruction Instruction) error {
-	switch instruction.(type) {
-	case *MovementInstruction:
-		err := r.Move(console, instruction.(*MovementInstruction).Distance)
-		if err != nil {
+func (r *Rover) Instruct(instruction Instruction) error {
+	r.Grid.Publish(InstructedEvent{Rover: r, Instruction: instruction})
+	outer := r.instruction
+	r.instruction = instruction
+	defer func() { r.instruction = outer }()
+
+	return r.Execute(instruction)
+}
+
+// loop
+// Executes the loop's body until the rover senses its condition, giving up after MaxLoopIterations passes.
+func (r *Rover) loop(loop *LoopInstruction) error {
+	for range MaxLoopIterations {
+		if r.Sense(loop.Until) {
+			return nil
+		}
+		if err := r.instructAll(loop.Body); err != nil {
 			return err
 		}
+	}
+	if r.Sense(loop.Until) {
 		return nil
-	case *RotationInstruction:
-		err := r.Rotate(instruction.(*RotationInstruction).Orientation)
-		if err != nil {
+	}
+	return &LoopLimitError{Until: loop.Until}
+}
+
+// instructAll
+// Executes the instructions of a conditional or loop in order, stopping at the first error.
+func (r *Rover) instructAll(instructions []Instruction) error {
+	for _, instruction := range instructions {
+		if err := r.Instruct(instruction); err != nil {
 			return err
 		}
-		return nil
-	default:
-		return fmt.Errorf("unknown instruction: %v", instruction)
 	}
+	return nil
 }
 
 func (r *Rover) CurrentPositionIsScented() bool {
@@ -98,11 +216,36 @@ func (r *Rover) CurrentPositionIsScented() bool {
 }
 
 func (r *Rover) OnGridExit() error {
-	r.Grid.AddScent(r.Position)
-	return fmt.Errorf("Your rover fell off the grid at (%d, %d)!\n", r.Position.X, r.Position.Y)
+	return r.exit(r.Direction)
+}
+
+// exit
+// Loses the rover off the grid, leaving a scent that remembers the heading it fell in.
+func (r *Rover) exit(heading Direction) error {
+	scent, err := r.Fall(heading)
+	r.Grid.LeaveScent(scent)
+	return err
+}
+
+// Fall
+// Loses the rover off the grid in the given heading and returns the scent it should leave, without adding it to the
+// grid. A rover reversing off the edge falls in the opposite heading to the one it faces.
+func (r *Rover) Fall(heading Direction) (Scent, error) {
+	r.Lost = true
+	r.Grid.Vacate(r)
+	r.Grid.Publish(FellOffEvent{Rover: r, Position: r.Position, Direction: r.Direction})
+	scent := Scent{Position: r.Position, Direction: heading, Instruction: r.instruction}
+	return scent, &LostError{Position: r.Position.Copy(), Direction: r.Direction}
 }
 
 type Movement struct {
 	Direction Direction
 	Distance  int
 }
+
+// Collision
+// Records a rover running into another rover, identified by its ID, at the given position.
+type Collision struct {
+	Position Position
+	Other    int
+}
@@ -1,9 +1,7 @@
 package mars
 
 import (
-	"bufio"
-	"marster-bot/output"
-	"strings"
+	"errors"
 	"testing"
 )
 
@@ -16,14 +14,12 @@ func (u UnknownInstruction) String() string {
 func (u UnknownInstruction) isInstruction() {}
 
 func TestRoverInstruct(t *testing.T) {
-	reader := bufio.NewReader(strings.NewReader(""))
-	console := output.NewConsole(*reader, false)
 	grid := NewGrid(10, 10)
 	rover := NewRover(5, 5, North, grid)
 
 	t.Run("MovementInstruction", func(t *testing.T) {
 		instruction := NewMovementInstruction(2)
-		err := rover.Instruct(console, instruction)
+		err := rover.Instruct(instruction)
 		if err != nil {
 			t.Errorf("Expected no error for MovementInstruction, got: %v", err)
 		}
@@ -35,7 +31,7 @@ func TestRoverInstruct(t *testing.T) {
 	t.Run("RotationInstruction", func(t *testing.T) {
 		instruction := NewOrientationInstruction(Right)
 		initialDirection := rover.Direction
-		err := rover.Instruct(console, instruction)
+		err := rover.Instruct(instruction)
 		if err != nil {
 			t.Errorf("Expected no error for RotationInstruction, got: %v", err)
 		}
@@ -46,9 +42,213 @@ func TestRoverInstruct(t *testing.T) {
 
 	t.Run("UnknownInstruction", func(t *testing.T) {
 		instruction := UnknownInstruction{}
-		err := rover.Instruct(console, instruction)
+		err := rover.Instruct(instruction)
 		if err == nil {
 			t.Errorf("Expected error for unknown instruction, got nil")
 		}
+		if !errors.Is(err, ErrUnknownInstruction) {
+			t.Errorf("Expected ErrUnknownInstruction, got: %v", err)
+		}
+	})
+}
+
+func TestRoverMove(t *testing.T) {
+
+	t.Run("Moves backwards without changing heading", func(t *testing.T) {
+		rover := NewRover(2, 2, North, NewGrid(5, 5))
+		if err := rover.Move(-2); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(2, 0)) || !rover.Direction.Equals(North) {
+			t.Errorf("Expected 2 0 N, got %d %d %s", rover.Position.X, rover.Position.Y, rover.Direction)
+		}
+	})
+
+	t.Run("Multi-step move is lost from the last safe cell", func(t *testing.T) {
+		grid := NewGrid(5, 5)
+		rover := NewRover(1, 3, North, grid)
+
+		err := rover.Move(5)
+		if !isLost(err) {
+			t.Fatalf("Expected rover to fall off, got: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(1, 5)) {
+			t.Errorf("Expected rover lost at (1,5), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+		if !grid.IsScented(NewPosition(1, 5)) {
+			t.Error("Expected scent at (1,5)")
+		}
+	})
+
+	t.Run("Multi-step move stops at a scented edge", func(t *testing.T) {
+		grid := NewGrid(5, 5)
+		grid.AddScent(NewPosition(0, 2), West)
+		rover := NewRover(3, 2, East, grid)
+
+		if err := rover.Move(-7); err != nil {
+			t.Fatalf("Expected scent to protect the rover, got: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(0, 2)) {
+			t.Errorf("Expected rover to stop at (0,2), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+	})
+
+	t.Run("Turn around reverses heading", func(t *testing.T) {
+		rover := NewRover(0, 0, East, NewGrid(5, 5))
+		rover.Rotate(Around)
+		if !rover.Direction.Equals(West) {
+			t.Errorf("Expected West, got %s", rover.Direction)
+		}
+	})
+}
+
+func TestRoverObstacles(t *testing.T) {
+
+	t.Run("Skip policy abandons the blocked move", func(t *testing.T) {
+		grid := NewGrid(5, 5)
+		grid.AddObstacle(NewPosition(2, 3))
+		rover := NewRover(2, 0, North, grid)
+
+		if err := rover.Move(5); err != nil {
+			t.Fatalf("Expected skipped move to succeed, got: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(2, 2)) {
+			t.Errorf("Expected rover to stop in front of the obstacle at (2,2), got (%d,%d)",
+				rover.Position.X, rover.Position.Y)
+		}
+	})
+
+	t.Run("Stop policy reports the obstacle", func(t *testing.T) {
+		grid := NewGrid(5, 5)
+		grid.ObstaclePolicy = ObstacleStop
+		grid.AddObstacle(NewPosition(3, 1))
+		rover := NewRover(1, 1, East, grid)
+
+		err := rover.Move(3)
+		var obstacleErr *ObstacleError
+		if !errors.As(err, &obstacleErr) {
+			t.Fatalf("Expected ObstacleError, got: %v", err)
+		}
+		if !obstacleErr.Position.Equals(NewPosition(3, 1)) {
+			t.Errorf("Expected obstacle at (3,1), got %s", obstacleErr.Position.String())
+		}
+		if !rover.Position.Equals(NewPosition(2, 1)) {
+			t.Errorf("Expected rover at (2,1), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+		if rover.Lost || grid.IsScented(rover.Position) {
+			t.Error("Expected an obstacle stop not to lose the rover or leave a scent")
+		}
+	})
+}
+
+func TestRoverEdges(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    EdgePolicy
+		start     Position
+		direction Direction
+		distance  int8
+		position  Position
+		facing    Direction
+	}{
+		{"Clamp stops at the wall", EdgeClamp, NewPosition(1, 2), North, 4, NewPosition(1, 3), North},
+		{"Clamp stops reversing into the wall", EdgeClamp, NewPosition(1, 1), North, -3, NewPosition(1, 0), North},
+		{"Bounce turns around and carries on", EdgeBounce, NewPosition(1, 2), North, 4, NewPosition(1, 1), South},
+		{"Bounce while reversing keeps reversing", EdgeBounce, NewPosition(0, 1), East, -2, NewPosition(1, 1), West},
+		{"Wrap re-enters on the opposite edge", EdgeWrap, NewPosition(3, 1), East, 2, NewPosition(1, 1), East},
+		{"Wrap re-enters below the bottom edge", EdgeWrap, NewPosition(2, 0), South, 1, NewPosition(2, 3), South},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := NewGrid(3, 3)
+			grid.EdgePolicy = tt.policy
+			rover := NewRover(tt.start.X, tt.start.Y, tt.direction, grid)
+
+			if err := rover.Move(tt.distance); err != nil {
+				t.Fatalf("Expected the edge to keep the rover on the grid, got: %v", err)
+			}
+			if !rover.Position.Equals(tt.position) || !rover.Direction.Equals(tt.facing) {
+				t.Errorf("Expected rover at (%d,%d) facing %s, got (%d,%d) facing %s",
+					tt.position.X, tt.position.Y, tt.facing, rover.Position.X, rover.Position.Y, rover.Direction)
+			}
+			if rover.Lost || len(grid.Scents()) != 0 {
+				t.Error("Expected no rover to be lost and no scent to be left")
+			}
+		})
+	}
+
+	t.Run("Wrap respects obstacles on the far side", func(t *testing.T) {
+		grid := NewGrid(3, 3)
+		grid.EdgePolicy = EdgeWrap
+		grid.AddObstacle(NewPosition(0, 1))
+		rover := NewRover(3, 1, East, grid)
+
+		if err := rover.Move(1); err != nil {
+			t.Fatalf("Expected skipped move to succeed, got: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(3, 1)) {
+			t.Errorf("Expected rover to stay at (3,1), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+	})
+}
+
+func TestRoverCollisions(t *testing.T) {
+
+	setup := func(policy CollisionPolicy) (*Grid, *Rover) {
+		grid := NewGrid(5, 5)
+		grid.CollisionPolicy = policy
+		parked := NewRover(3, 1, North, grid)
+		parked.ID = 1
+		grid.Occupy(parked)
+
+		mover := NewRover(1, 1, East, grid)
+		mover.ID = 2
+		grid.Occupy(mover)
+		return grid, mover
+	}
+
+	t.Run("Block policy stops in front of the other rover", func(t *testing.T) {
+		_, rover := setup(CollisionBlock)
+
+		if err := rover.Move(3); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(2, 1)) {
+			t.Errorf("Expected rover at (2,1), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+		if len(rover.Collisions) != 1 || rover.Collisions[0].Other != 1 {
+			t.Errorf("Expected one collision with rover #1, got %v", rover.Collisions)
+		}
+	})
+
+	t.Run("Abort policy names both rovers", func(t *testing.T) {
+		_, rover := setup(CollisionAbort)
+
+		err := rover.Move(3)
+		var collisionErr *CollisionError
+		if !errors.As(err, &collisionErr) {
+			t.Fatalf("Expected CollisionError, got: %v", err)
+		}
+		if collisionErr.Rover != 2 || collisionErr.Other != 1 || !collisionErr.Position.Equals(NewPosition(3, 1)) {
+			t.Errorf("Unexpected collision: %v", collisionErr)
+		}
+	})
+
+	t.Run("Allow policy moves through and keeps occupancy in step", func(t *testing.T) {
+		grid, rover := setup(CollisionAllow)
+
+		if err := rover.Move(3); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !rover.Position.Equals(NewPosition(4, 1)) {
+			t.Errorf("Expected rover at (4,1), got (%d,%d)", rover.Position.X, rover.Position.Y)
+		}
+		if len(rover.Collisions) != 1 {
+			t.Errorf("Expected one recorded collision, got %d", len(rover.Collisions))
+		}
+		if grid.OccupantAt(NewPosition(4, 1), nil) != rover || grid.OccupantAt(NewPosition(1, 1), nil) != nil {
+			t.Error("Expected the rover's occupancy to follow it")
+		}
 	})
 }
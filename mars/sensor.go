@@ -0,0 +1,58 @@
+package mars
+
+// Condition
+// Something a rover can sense about the cell ahead of it, used by conditional and loop instructions.
+type Condition uint8
+
+const (
+	// ConditionOffGrid holds when the cell ahead is outside the grid, whatever the edge policy does about it.
+	ConditionOffGrid Condition = iota
+	// ConditionScented holds when the cell ahead is outside the grid and a scent protects that exit.
+	ConditionScented
+	// ConditionBlocked holds when an obstacle or another rover is in the cell a step forward leads to.
+	ConditionBlocked
+	// ConditionClear holds when a step forward would move the rover into a free cell.
+	ConditionClear
+)
+
+var conditionNames = map[Condition]string{
+	ConditionOffGrid: "offgrid",
+	ConditionScented: "scented",
+	ConditionBlocked: "blocked",
+	ConditionClear:   "clear",
+}
+
+func (c Condition) String() string {
+	return conditionNames[c]
+}
+
+func ConditionFromName(name string) (Condition, bool) {
+	for condition, conditionName := range conditionNames {
+		if conditionName == name {
+			return condition, true
+		}
+	}
+	return ConditionOffGrid, false
+}
+
+// Sense
+// Reports whether the condition holds for the cell ahead of the rover, looking in the heading it faces. Sensing
+// never moves the rover or publishes events.
+func (r *Rover) Sense(condition Condition) bool {
+	ahead := r.Position.Step(r.Direction)
+	offGrid := !r.Grid.PositionWithinBoundsXY(ahead.X, ahead.Y)
+
+	switch condition {
+	case ConditionOffGrid:
+		return offGrid
+	case ConditionScented:
+		return offGrid && r.Grid.IsExitScented(r.Position, r.Direction)
+	}
+
+	step := r.PlanStep(r.Direction)
+	occupied := (step.Kind == StepMove || step.Kind == StepWrap) && r.Grid.OccupantAt(step.To, r) != nil
+	if condition == ConditionBlocked {
+		return step.Kind == StepObstacle || occupied
+	}
+	return (step.Kind == StepMove || step.Kind == StepWrap) && !occupied
+}
@@ -0,0 +1,143 @@
+package mars
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoverSense(t *testing.T) {
+	grid := NewGrid(5, 3)
+	grid.AddObstacle(NewPosition(2, 2))
+	grid.AddScent(NewPosition(0, 3), North)
+	parked := NewRover(4, 1, North, grid)
+	grid.Occupy(parked)
+
+	tests := []struct {
+		name      string
+		x, y      int8
+		direction Direction
+		edge      EdgePolicy
+		want      map[Condition]bool
+	}{
+		{"Open ground", 1, 1, North, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: false, ConditionScented: false, ConditionBlocked: false, ConditionClear: true}},
+		{"Obstacle ahead", 2, 1, North, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: false, ConditionScented: false, ConditionBlocked: true, ConditionClear: false}},
+		{"Rover ahead", 3, 1, East, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: false, ConditionScented: false, ConditionBlocked: true, ConditionClear: false}},
+		{"Edge ahead", 5, 0, East, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: true, ConditionScented: false, ConditionBlocked: false, ConditionClear: false}},
+		{"Scented edge ahead", 0, 3, North, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: true, ConditionScented: true, ConditionBlocked: false, ConditionClear: false}},
+		{"Scent for another heading", 0, 3, West, EdgeLost,
+			map[Condition]bool{ConditionOffGrid: true, ConditionScented: false, ConditionBlocked: false, ConditionClear: false}},
+		{"Wrapping edge ahead", 5, 0, East, EdgeWrap,
+			map[Condition]bool{ConditionOffGrid: true, ConditionScented: false, ConditionBlocked: false, ConditionClear: true}},
+		{"Wall ahead", 5, 0, East, EdgeClamp,
+			map[Condition]bool{ConditionOffGrid: true, ConditionScented: false, ConditionBlocked: false, ConditionClear: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid.EdgePolicy = tt.edge
+			defer func() { grid.EdgePolicy = EdgeLost }()
+
+			rover := NewRover(tt.x, tt.y, tt.direction, grid)
+			for condition, want := range tt.want {
+				if got := rover.Sense(condition); got != want {
+					t.Errorf("Expected %s to be %v, got %v", condition, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRoverConditionals(t *testing.T) {
+	forward := NewMovementInstruction(1)
+	right := NewOrientationInstruction(Right)
+
+	t.Run("If takes the branch for what is sensed", func(t *testing.T) {
+		grid := NewGrid(5, 3)
+		grid.AddObstacle(NewPosition(1, 2))
+		instruction := &ConditionalInstruction{Condition: ConditionBlocked, Then: []Instruction{right}, Else: []Instruction{forward}}
+
+		rover := NewRover(1, 0, North, grid)
+		for range 3 {
+			if err := rover.Instruct(instruction); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if rover.Position != NewPosition(2, 1) || rover.Direction != East {
+			t.Errorf("Expected 2 1 E, got %d %d %s", rover.Position.X, rover.Position.Y, rover.Direction)
+		}
+	})
+
+	t.Run("If without else does nothing when the condition fails", func(t *testing.T) {
+		rover := NewRover(1, 1, North, NewGrid(5, 3))
+		if err := rover.Instruct(&ConditionalInstruction{Condition: ConditionOffGrid, Then: []Instruction{forward}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if rover.Position != NewPosition(1, 1) {
+			t.Errorf("Expected the rover to stay at (1,1), got %s", rover.Position.String())
+		}
+	})
+
+	t.Run("Until runs the body until the condition holds", func(t *testing.T) {
+		grid := NewGrid(5, 3)
+		grid.AddObstacle(NewPosition(4, 1))
+		rover := NewRover(0, 1, East, grid)
+
+		if err := rover.Instruct(&LoopInstruction{Until: ConditionBlocked, Body: []Instruction{forward}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if rover.Position != NewPosition(3, 1) {
+			t.Errorf("Expected the rover to stop in front of the obstacle at (3,1), got %s", rover.Position.String())
+		}
+	})
+
+	t.Run("Until that already holds runs nothing", func(t *testing.T) {
+		rover := NewRover(5, 1, East, NewGrid(5, 3))
+		if err := rover.Instruct(&LoopInstruction{Until: ConditionOffGrid, Body: []Instruction{forward}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if rover.Position != NewPosition(5, 1) || rover.Lost {
+			t.Errorf("Expected the rover to stay at (5,1), got %s", rover.Position.String())
+		}
+	})
+
+	t.Run("Until gives up after the iteration limit", func(t *testing.T) {
+		rover := NewRover(2, 1, North, NewGrid(5, 3))
+		err := rover.Instruct(&LoopInstruction{Until: ConditionOffGrid, Body: []Instruction{right}})
+
+		var loopErr *LoopLimitError
+		if !errors.As(err, &loopErr) || loopErr.Until != ConditionOffGrid {
+			t.Errorf("Expected a LoopLimitError, got %v", err)
+		}
+	})
+
+	t.Run("Scent records the instruction inside the branch", func(t *testing.T) {
+		grid := NewGrid(5, 3)
+		rover := NewRover(0, 3, North, grid)
+		err := rover.Instruct(&ConditionalInstruction{Condition: ConditionOffGrid, Then: []Instruction{forward}})
+
+		var lostErr *LostError
+		if !errors.As(err, &lostErr) {
+			t.Fatalf("Expected the rover to be lost, got %v", err)
+		}
+		scents := grid.ExitScents()
+		if len(scents) != 1 || scents[0].Instruction != forward {
+			t.Errorf("Expected a scent recording the forward move, got %+v", scents)
+		}
+	})
+
+	t.Run("Code round trips the language", func(t *testing.T) {
+		instruction := &ConditionalInstruction{Condition: ConditionBlocked, Then: []Instruction{right},
+			Else: []Instruction{&LoopInstruction{Until: ConditionClear, Body: []Instruction{right}}}}
+		if got, want := InstructionCode(instruction), "IF BLOCKED (R) ELSE (UNTIL CLEAR (R))"; got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		if got := InstructionCount([]Instruction{instruction, forward}); got != 5 {
+			t.Errorf("Expected 5 instructions counting nested ones, got %d", got)
+		}
+	})
+}
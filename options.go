@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+// gridFlags
+// Flags shared by every mode that configure the grid a simulation runs on.
+func gridFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "obstacle",
+			Usage: "Block the cell at `x,y` (repeatable)",
+		},
+		&cli.StringFlag{
+			Name:  "on-obstacle",
+			Usage: "What a rover does when it meets an obstacle: skip the move or stop",
+			Value: mars.ObstacleSkip.String(),
+		},
+		&cli.StringFlag{
+			Name:  "on-collision",
+			Usage: "What a rover does when it meets another rover: block the move, abort the rover, or allow and warn",
+			Value: mars.CollisionBlock.String(),
+		},
+		&cli.StringFlag{
+			Name:  "edge",
+			Usage: "What happens at the edge of the grid: lost, clamp, bounce or wrap (overrides a mission's edge line)",
+			Value: mars.EdgeLost.String(),
+		},
+		&cli.StringFlag{
+			Name:  "scent-mode",
+			Usage: "Which moves off the grid a scent protects: exit (only the heading the rover fell) or position (any)",
+			Value: mars.ScentByExit.String(),
+		},
+		&cli.StringFlag{
+			Name:  "scents-in",
+			Usage: "Load scents left by earlier sessions from `file`",
+		},
+		&cli.StringFlag{
+			Name:  "scents-out",
+			Usage: "Save the grid's scents to `file` when the session ends",
+		},
+	}
+}
+
+// gridOptions
+// Grid configuration collected from the command line and applied to each grid once it is created.
+type gridOptions struct {
+	obstaclePolicy  mars.ObstaclePolicy
+	collisionPolicy mars.CollisionPolicy
+	edgePolicy      *mars.EdgePolicy // nil unless --edge was given, so a mission file's edge line is kept
+	scentMode       mars.ScentMode
+	obstacles       []string
+	scentsIn        string
+	scentsOut       string
+}
+
+func gridOptionsFromFlags(c *cli.Command) (*gridOptions, error) {
+	policy, ok := mars.ObstaclePolicyFromName(c.String("on-obstacle"))
+	if !ok {
+		return nil, fmt.Errorf("invalid --on-obstacle '%s': must be skip or stop", c.String("on-obstacle"))
+	}
+
+	collisionPolicy, ok := mars.CollisionPolicyFromName(c.String("on-collision"))
+	if !ok {
+		return nil, fmt.Errorf("invalid --on-collision '%s': must be block, abort or allow", c.String("on-collision"))
+	}
+
+	var edgePolicy *mars.EdgePolicy
+	if c.IsSet("edge") {
+		policy, ok := mars.EdgePolicyFromName(c.String("edge"))
+		if !ok {
+			return nil, fmt.Errorf("invalid --edge '%s': must be lost, clamp, bounce or wrap", c.String("edge"))
+		}
+		edgePolicy = &policy
+	}
+
+	scentMode, ok := mars.ScentModeFromName(c.String("scent-mode"))
+	if !ok {
+		return nil, fmt.Errorf("invalid --scent-mode '%s': must be exit or position", c.String("scent-mode"))
+	}
+
+	return &gridOptions{
+		obstaclePolicy:  policy,
+		collisionPolicy: collisionPolicy,
+		edgePolicy:      edgePolicy,
+		scentMode:       scentMode,
+		obstacles:       c.StringSlice("obstacle"),
+		scentsIn:        c.String("scents-in"),
+		scentsOut:       c.String("scents-out"),
+	}, nil
+}
+
+func (o *gridOptions) apply(grid *mars.Grid) error {
+	grid.ObstaclePolicy = o.obstaclePolicy
+	grid.CollisionPolicy = o.collisionPolicy
+	grid.ScentMode = o.scentMode
+	if o.edgePolicy != nil {
+		grid.EdgePolicy = *o.edgePolicy
+	}
+
+	for _, obstacle := range o.obstacles {
+		if err := input.ParseObstacle(obstacle, grid); err != nil {
+			return fmt.Errorf("--obstacle: %w", err)
+		}
+	}
+
+	if o.scentsIn != "" {
+		if err := loadScents(o.scentsIn, grid); err != nil {
+			return fmt.Errorf("--scents-in: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyMission
+// Applies the options to a parsed mission's grid, then checks that no rover starts on an obstacle given with
+// --obstacle.
+func (o *gridOptions) applyMission(mission *input.Mission) error {
+	if err := o.apply(mission.Grid); err != nil {
+		return err
+	}
+	if err := mission.CheckStarts(); err != nil {
+		return fmt.Errorf("--obstacle: %w", err)
+	}
+	return nil
+}
+
+// save
+// Writes the grid's scents to the --scents-out file, if one was given.
+func (o *gridOptions) save(grid *mars.Grid) error {
+	if o.scentsOut == "" {
+		return nil
+	}
+
+	file, err := os.Create(o.scentsOut)
+	if err != nil {
+		return fmt.Errorf("--scents-out: %w", err)
+	}
+	defer file.Close()
+
+	if err := output.WriteScentMap(file, engine.ScentMap(grid)); err != nil {
+		return fmt.Errorf("--scents-out: %w", err)
+	}
+	return file.Close()
+}
+
+// record
+// Describes the options for a session transcript. --scents-out is left out, as a replay must not overwrite it.
+func (o *gridOptions) record() output.SessionOptionsRecord {
+	record := output.SessionOptionsRecord{
+		ObstaclePolicy:  o.obstaclePolicy.String(),
+		CollisionPolicy: o.collisionPolicy.String(),
+		ScentMode:       o.scentMode.String(),
+		Obstacles:       append([]string{}, o.obstacles...),
+		ScentsIn:        o.scentsIn,
+	}
+	if o.edgePolicy != nil {
+		record.EdgePolicy = o.edgePolicy.String()
+	}
+	return record
+}
+
+// gridOptionsFromRecord
+// Rebuilds the options a transcript was recorded with.
+func gridOptionsFromRecord(record output.SessionOptionsRecord) (*gridOptions, error) {
+	policy, ok := mars.ObstaclePolicyFromName(record.ObstaclePolicy)
+	if !ok {
+		return nil, fmt.Errorf("invalid obstacle policy '%s'", record.ObstaclePolicy)
+	}
+
+	collisionPolicy, ok := mars.CollisionPolicyFromName(record.CollisionPolicy)
+	if !ok {
+		return nil, fmt.Errorf("invalid collision policy '%s'", record.CollisionPolicy)
+	}
+
+	var edgePolicy *mars.EdgePolicy
+	if record.EdgePolicy != "" {
+		policy, ok := mars.EdgePolicyFromName(record.EdgePolicy)
+		if !ok {
+			return nil, fmt.Errorf("invalid edge policy '%s'", record.EdgePolicy)
+		}
+		edgePolicy = &policy
+	}
+
+	scentMode, ok := mars.ScentModeFromName(record.ScentMode)
+	if !ok {
+		return nil, fmt.Errorf("invalid scent mode '%s'", record.ScentMode)
+	}
+
+	return &gridOptions{
+		obstaclePolicy:  policy,
+		collisionPolicy: collisionPolicy,
+		edgePolicy:      edgePolicy,
+		scentMode:       scentMode,
+		obstacles:       record.Obstacles,
+		scentsIn:        record.ScentsIn,
+	}, nil
+}
+
+func loadScents(path string, grid *mars.Grid) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scentMap, err := output.ReadScentMap(file)
+	if err != nil {
+		return err
+	}
+	return input.LoadScentMap(grid, scentMap)
+}
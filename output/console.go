@@ -11,10 +11,20 @@ import (
 )
 
 type Console struct {
-	writer io.Writer
-	colors colorsConfig
-	reader bufio.Reader
-	debug  bool
+	writer   io.Writer
+	colors   colorsConfig
+	reader   bufio.Reader
+	debug    bool
+	commands func(line string) bool
+	observer PromptObserver
+}
+
+// PromptObserver
+// Watches every prompt: Prompting is called before the prompt is shown and Answered once a line has been read,
+// including lines that turn out to be meta-commands.
+type PromptObserver interface {
+	Prompting(text string)
+	Answered(text string, answer string)
 }
 
 type colorsConfig struct {
@@ -46,6 +56,29 @@ func NewConsole(reader bufio.Reader, debug bool) *Console {
 	}
 }
 
+func (c *Console) SetWriter(writer io.Writer) {
+	c.writer = writer
+}
+
+// SetDebug
+// Turns debug output on or off.
+func (c *Console) SetDebug(debug bool) {
+	c.debug = debug
+}
+
+// SetCommandHandler
+// Offers every line read by Prompt to the handler first. Lines the handler reports as handled are not returned;
+// the prompt is shown again instead.
+func (c *Console) SetCommandHandler(handler func(line string) bool) {
+	c.commands = handler
+}
+
+// SetPromptObserver
+// Registers an observer for every prompt and answer.
+func (c *Console) SetPromptObserver(observer PromptObserver) {
+	c.observer = observer
+}
+
 func (c *Console) Header(text string) {
 	c.colors.Header.Fprintln(c.writer, text)
 }
@@ -77,16 +110,26 @@ func (c *Console) Info(format string, args ...interface{}) {
 }
 
 func (c *Console) Prompt(text string) (string, error) {
-	c.colors.Prompt.Fprint(c.writer, "▸ "+text)
-
-	response, err := c.reader.ReadString('\n')
-	if err != nil {
-		return "", err
+	for {
+		if c.observer != nil {
+			c.observer.Prompting(text)
+		}
+		c.colors.Prompt.Fprint(c.writer, "▸ "+text)
+
+		response, err := c.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		response = strings.TrimSpace(response)
+		if c.observer != nil {
+			c.observer.Answered(text, response)
+		}
+
+		if c.commands != nil && c.commands(response) {
+			continue
+		}
+		return response, nil
 	}
-	response = strings.TrimSpace(response)
-
-	return response, nil
-
 }
 
 func (c *Console) Data(label string, value interface{}) {
@@ -0,0 +1,220 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultColumns = 80
+	defaultRows    = 24
+	mapLegend      = "^>v< rover  X lost  * scent  # obstacle  1-9 A-Z a-z trail"
+	// trailSymbols marks the trails of rovers #1 onwards, one symbol each, skipping the X and v already used for
+	// lost rovers and south-facing rovers.
+	trailSymbols = "123456789ABCDEFGHIJKLMNOPQRSTUVWYZabcdefghijklmnopqrstuwxyz"
+	// overflowSymbol marks the trails of every rover numbered past the last trail symbol.
+	overflowSymbol = '&'
+)
+
+// trailSymbol
+// Returns the symbol a rover's trail is drawn with.
+func trailSymbol(number int) rune {
+	if number < 1 || number > len(trailSymbols) {
+		return overflowSymbol
+	}
+	return rune(trailSymbols[number-1])
+}
+
+// TrailRecord
+// The cells a rover passed through, from its start to its final pose.
+type TrailRecord struct {
+	Number int
+	Points []PointRecord
+	Final  PoseRecord
+	Lost   bool
+}
+
+// GridMap
+// Everything drawn on a map: the grid's size in cells, its edge policy, its scents and obstacles, and each rover's
+// trail.
+type GridMap struct {
+	Width     int
+	Height    int
+	Edge      string
+	Scents    []PointRecord
+	Obstacles []PointRecord
+	Trails    []TrailRecord
+}
+
+// MapView
+// The part of the map to draw. Columns and Rows are the space available in characters and default to the terminal
+// size. When the grid does not fit it is cropped to a window around Center, which defaults to the last rover.
+type MapView struct {
+	Columns int
+	Rows    int
+	Center  *PointRecord
+}
+
+// mapEdges
+// The horizontal and vertical border characters for each edge policy, and a note explaining it. Edges where rovers
+// are lost use the plain border and need no note.
+var mapEdges = map[string]struct {
+	horizontal string
+	vertical   string
+	note       string
+}{
+	"clamp":  {"=", "|", "edges: clamp (walls stop rovers)"},
+	"bounce": {"=", "|", "edges: bounce (rovers turn around at walls)"},
+	"wrap":   {"~", ":", "edges: wrap (rovers re-enter on the opposite side)"},
+}
+
+var headingArrows = map[string]rune{
+	"N": '^',
+	"E": '>',
+	"S": 'v',
+	"W": '<',
+}
+
+// RenderMap
+// Draws the grid as lines of text with north at the top.
+func RenderMap(m GridMap, view MapView) []string {
+	cells := make([][]rune, m.Height)
+	for y := range cells {
+		cells[y] = []rune(strings.Repeat(".", m.Width))
+	}
+	set := func(x, y int, symbol rune) {
+		if x >= 0 && y >= 0 && x < m.Width && y < m.Height {
+			cells[y][x] = symbol
+		}
+	}
+
+	overflow := false
+	for _, trail := range m.Trails {
+		symbol := trailSymbol(trail.Number)
+		overflow = overflow || symbol == overflowSymbol
+		for _, point := range trail.Points {
+			set(point.X, point.Y, symbol)
+		}
+	}
+	for _, scent := range m.Scents {
+		set(scent.X, scent.Y, '*')
+	}
+	for _, obstacle := range m.Obstacles {
+		set(obstacle.X, obstacle.Y, '#')
+	}
+	for _, trail := range m.Trails {
+		symbol := headingArrows[trail.Final.Direction]
+		if trail.Lost {
+			symbol = 'X'
+		}
+		set(trail.Final.X, trail.Final.Y, symbol)
+	}
+
+	columns, rows := view.Columns, view.Rows
+	if columns == 0 || rows == 0 {
+		columns, rows = viewportSize()
+	}
+
+	horizontal, vertical := "-", "|"
+	edge, hasEdge := mapEdges[m.Edge]
+	if hasEdge {
+		horizontal, vertical = edge.horizontal, edge.vertical
+	}
+
+	labelWidth := len(strconv.Itoa(m.Height - 1))
+	// Each cell takes two characters, plus the row label and the borders either side.
+	visibleX := max(1, min(m.Width, (columns-labelWidth-4)/2))
+	// Leave room for the borders, column labels, legend, cropping note and edge note.
+	reserved := 5
+	if hasEdge {
+		reserved++
+	}
+	if overflow {
+		reserved++
+	}
+	visibleY := max(1, min(m.Height, rows-reserved))
+
+	center := view.Center
+	if center == nil && len(m.Trails) > 0 {
+		final := m.Trails[len(m.Trails)-1].Final
+		center = &PointRecord{X: final.X, Y: final.Y}
+	}
+	if center == nil {
+		center = &PointRecord{}
+	}
+	minX := windowStart(center.X, visibleX, m.Width)
+	minY := windowStart(center.Y, visibleY, m.Height)
+	maxX, maxY := minX+visibleX-1, minY+visibleY-1
+
+	padding := strings.Repeat(" ", labelWidth)
+	border := padding + " +" + strings.Repeat(horizontal, visibleX*2+1) + "+"
+
+	lines := []string{border}
+	for y := maxY; y >= minY; y-- {
+		var row strings.Builder
+		fmt.Fprintf(&row, "%*d %s ", labelWidth, y, vertical)
+		for x := minX; x <= maxX; x++ {
+			row.WriteRune(cells[y][x])
+			row.WriteRune(' ')
+		}
+		row.WriteString(vertical)
+		lines = append(lines, row.String())
+	}
+	lines = append(lines, border)
+
+	var labels strings.Builder
+	labels.WriteString(padding + "   ")
+	for x := minX; x <= maxX; x++ {
+		fmt.Fprintf(&labels, "%d ", x%10)
+	}
+	lines = append(lines, strings.TrimRight(labels.String(), " "))
+
+	if visibleX < m.Width || visibleY < m.Height {
+		lines = append(lines, fmt.Sprintf("showing x %d-%d of 0-%d, y %d-%d of 0-%d",
+			minX, maxX, m.Width-1, minY, maxY, m.Height-1))
+	}
+	lines = append(lines, mapLegend)
+	if overflow {
+		lines = append(lines, fmt.Sprintf("%c trails of rovers after #%d", overflowSymbol, len(trailSymbols)))
+	}
+	if hasEdge {
+		lines = append(lines, edge.note)
+	}
+
+	return lines
+}
+
+// windowStart
+// Returns the first cell of a window of the given size centred on a cell, kept within the grid.
+func windowStart(center, size, total int) int {
+	start := center - size/2
+	return max(0, min(start, total-size))
+}
+
+// viewportSize
+// Returns the terminal size in characters, falling back to $COLUMNS/$LINES and then 80x24.
+func viewportSize() (int, int) {
+	if columns, rows, ok := terminalSize(); ok {
+		return columns, rows
+	}
+
+	columns, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || columns <= 0 {
+		columns = defaultColumns
+	}
+	rows, err := strconv.Atoi(os.Getenv("LINES"))
+	if err != nil || rows <= 0 {
+		rows = defaultRows
+	}
+	return columns, rows
+}
+
+// Map
+// Prints the rendered grid map.
+func (c *Console) Map(m GridMap, view MapView) {
+	for _, line := range RenderMap(m, view) {
+		c.colors.Info.Fprintln(c.writer, line)
+	}
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMap(t *testing.T) {
+	t.Run("Draws scents, obstacles, trails and headings", func(t *testing.T) {
+		m := GridMap{
+			Width:     4,
+			Height:    3,
+			Scents:    []PointRecord{{X: 3, Y: 2}},
+			Obstacles: []PointRecord{{X: 0, Y: 2}},
+			Trails: []TrailRecord{
+				{
+					Number: 1,
+					Points: []PointRecord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}},
+					Final:  PoseRecord{X: 2, Y: 0, Direction: "N"},
+				},
+				{
+					Number: 2,
+					Points: []PointRecord{{X: 3, Y: 0}, {X: 3, Y: 1}, {X: 3, Y: 2}},
+					Final:  PoseRecord{X: 3, Y: 2, Direction: "N"},
+					Lost:   true,
+				},
+			},
+		}
+
+		lines := RenderMap(m, MapView{Columns: 80, Rows: 24})
+
+		expected := []string{
+			"  +---------+",
+			"2 | # . . X |",
+			"1 | . . . 2 |",
+			"0 | 1 1 ^ 2 |",
+			"  +---------+",
+			"    0 1 2 3",
+			mapLegend,
+		}
+		if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Unexpected map:\n%s\nexpected:\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+		}
+	})
+
+	t.Run("Draws the edge policy on the border", func(t *testing.T) {
+		m := GridMap{
+			Width:  2,
+			Height: 2,
+			Edge:   "wrap",
+			Trails: []TrailRecord{{Number: 1, Points: []PointRecord{{X: 1, Y: 0}, {X: 0, Y: 0}}, Final: PoseRecord{X: 0, Y: 0, Direction: "E"}}},
+		}
+
+		lines := RenderMap(m, MapView{Columns: 80, Rows: 24})
+
+		expected := []string{
+			"  +~~~~~+",
+			"1 : . . :",
+			"0 : > 1 :",
+			"  +~~~~~+",
+			"    0 1",
+			mapLegend,
+			"edges: wrap (rovers re-enter on the opposite side)",
+		}
+		if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Unexpected map:\n%s\nexpected:\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+		}
+	})
+
+	t.Run("Keeps trail symbols unique past rover 9", func(t *testing.T) {
+		m := GridMap{Width: 5, Height: 1}
+		for _, number := range []int{9, 10, 34, 35, 60} {
+			x := len(m.Trails)
+			m.Trails = append(m.Trails, TrailRecord{
+				Number: number,
+				Points: []PointRecord{{X: x, Y: 0}},
+				Final:  PoseRecord{X: -1, Y: -1, Direction: "N"},
+			})
+		}
+
+		lines := RenderMap(m, MapView{Columns: 80, Rows: 24})
+
+		if lines[1] != "0 | 9 A Z a & |" {
+			t.Errorf("Unexpected trail symbols: %q", lines[1])
+		}
+		if note := lines[len(lines)-1]; note != "& trails of rovers after #59" {
+			t.Errorf("Expected a note for the shared symbol, got %q", note)
+		}
+	})
+
+	t.Run("Crops large grids around the centre", func(t *testing.T) {
+		m := GridMap{
+			Width:  100,
+			Height: 50,
+			Trails: []TrailRecord{{Number: 1, Final: PoseRecord{X: 60, Y: 40, Direction: "E"}}},
+		}
+
+		lines := RenderMap(m, MapView{Columns: 30, Rows: 10})
+
+		// 30 columns leave room for 12 cells beside a two-digit row label, 10 rows leave 5 grid rows
+		if len(lines) != 10 {
+			t.Fatalf("Expected 10 lines, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+		}
+		for _, line := range lines[:len(lines)-2] {
+			if len(line) > 30 {
+				t.Errorf("Expected line to fit in 30 columns, got %d: %q", len(line), line)
+			}
+		}
+		if !strings.Contains(lines[3], ">") {
+			t.Errorf("Expected the rover to be in the middle row, got %q", lines[3])
+		}
+		if lines[len(lines)-2] != "showing x 54-65 of 0-99, y 38-42 of 0-49" {
+			t.Errorf("Unexpected cropping note: %q", lines[len(lines)-2])
+		}
+	})
+
+	t.Run("Window stays within the grid", func(t *testing.T) {
+		m := GridMap{Width: 100, Height: 50}
+
+		lines := RenderMap(m, MapView{Columns: 30, Rows: 10, Center: &PointRecord{X: 99, Y: 0}})
+
+		if lines[len(lines)-2] != "showing x 88-99 of 0-99, y 0-4 of 0-49" {
+			t.Errorf("Unexpected cropping note: %q", lines[len(lines)-2])
+		}
+	})
+}
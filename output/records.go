@@ -0,0 +1,231 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SchemaVersion
+// Identifies the layout of JSON records. Bump it whenever a field is renamed or removed so results can be compared
+// across releases.
+const SchemaVersion = "marster-bot/v1"
+
+type PointRecord struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type PoseRecord struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+}
+
+// GridRecord
+// Describes a grid by its upper-right coordinates, as given on the first line of a mission file, so a grid entered
+// as '5 3' has 6x4 cells.
+type GridRecord struct {
+	MaxX            int           `json:"max_x"`
+	MaxY            int           `json:"max_y"`
+	ObstaclePolicy  string        `json:"obstacle_policy"`
+	CollisionPolicy string        `json:"collision_policy"`
+	EdgePolicy      string        `json:"edge_policy"`
+	ScentMode       string        `json:"scent_mode"`
+	Obstacles       []PointRecord `json:"obstacles"`
+	Scents          []PointRecord `json:"scents"`
+}
+
+type CollisionRecord struct {
+	X     int `json:"x"`
+	Y     int `json:"y"`
+	Rover int `json:"rover"`
+	Other int `json:"other"`
+}
+
+// Kinds of failure reported in RoverRecord.ErrorKind.
+const (
+	ErrorKindLost               = "lost"
+	ErrorKindObstacle           = "obstacle"
+	ErrorKindCollision          = "collision"
+	ErrorKindUnknownInstruction = "unknown_instruction"
+	ErrorKindLoopLimit          = "loop_limit"
+	ErrorKindOther              = "other"
+)
+
+type RoverRecord struct {
+	Number       int               `json:"number"`
+	Start        PoseRecord        `json:"start"`
+	Instructions []string          `json:"instructions"`
+	Final        PoseRecord        `json:"final"`
+	Lost         bool              `json:"lost"`
+	ScentAdded   *PointRecord      `json:"scent_added"`
+	Obstacle     *PointRecord      `json:"obstacle"`
+	Collisions   []CollisionRecord `json:"collisions"`
+	Error        *string           `json:"error"`
+	ErrorKind    *string           `json:"error_kind"`
+}
+
+type TickPoseRecord struct {
+	Number    int    `json:"number"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+	Status    string `json:"status"`
+}
+
+// TickRecord
+// Every rover's pose at the end of a lock-step tick.
+type TickRecord struct {
+	Tick   int              `json:"tick"`
+	Rovers []TickPoseRecord `json:"rovers"`
+}
+
+type IgnoredMoveRecord struct {
+	Index       int    `json:"index"`
+	Instruction string `json:"instruction"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Direction   string `json:"direction"`
+}
+
+// CheckRecord
+// The result of dry-running an instruction string: the pose the rover would end in, the index of the instruction
+// that would lose it, and the moves scents would ignore.
+type CheckRecord struct {
+	Schema       string              `json:"schema"`
+	Start        PoseRecord          `json:"start"`
+	Instructions []string            `json:"instructions"`
+	Final        PoseRecord          `json:"final"`
+	Lost         bool                `json:"lost"`
+	LostAt       *int                `json:"lost_at"`
+	Ignored      []IgnoredMoveRecord `json:"ignored"`
+	Error        *string             `json:"error"`
+	ErrorKind    *string             `json:"error_kind"`
+}
+
+// RecordWriter
+// Writes simulation records in a machine-readable format.
+type RecordWriter interface {
+	WriteGrid(grid GridRecord) error
+	WriteTick(tick TickRecord) error
+	WriteRover(rover RoverRecord) error
+	Close() error
+}
+
+// NewRecordWriter
+// Returns a writer for the named format: 'json' writes a single document once closed, while 'ndjson' streams one
+// record per line as they arrive.
+func NewRecordWriter(format string, writer io.Writer) (RecordWriter, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{writer: writer, document: jsonDocument{Schema: SchemaVersion, Rovers: []RoverRecord{}}}, nil
+	case "ndjson":
+		return &ndjsonWriter{encoder: json.NewEncoder(writer)}, nil
+	default:
+		return nil, fmt.Errorf("unknown record format '%s'", format)
+	}
+}
+
+type jsonDocument struct {
+	Schema   string        `json:"schema"`
+	Grid     *GridRecord   `json:"grid"`
+	Timeline []TickRecord  `json:"timeline,omitempty"`
+	Rovers   []RoverRecord `json:"rovers"`
+}
+
+type jsonWriter struct {
+	writer   io.Writer
+	document jsonDocument
+}
+
+func (w *jsonWriter) WriteGrid(grid GridRecord) error {
+	w.document.Grid = &grid
+	return nil
+}
+
+func (w *jsonWriter) WriteTick(tick TickRecord) error {
+	w.document.Timeline = append(w.document.Timeline, tick)
+	return nil
+}
+
+func (w *jsonWriter) WriteRover(rover RoverRecord) error {
+	w.document.Rovers = append(w.document.Rovers, rover)
+	return nil
+}
+
+func (w *jsonWriter) Close() error {
+	encoder := json.NewEncoder(w.writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(w.document)
+}
+
+type ndjsonLine struct {
+	Schema string `json:"schema"`
+	Type   string `json:"type"`
+	*GridRecord
+	*TickRecord
+	*RoverRecord
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) WriteGrid(grid GridRecord) error {
+	return w.encoder.Encode(ndjsonLine{Schema: SchemaVersion, Type: "grid", GridRecord: &grid})
+}
+
+func (w *ndjsonWriter) WriteTick(tick TickRecord) error {
+	return w.encoder.Encode(ndjsonLine{Schema: SchemaVersion, Type: "tick", TickRecord: &tick})
+}
+
+func (w *ndjsonWriter) WriteRover(rover RoverRecord) error {
+	return w.encoder.Encode(ndjsonLine{Schema: SchemaVersion, Type: "rover", RoverRecord: &rover})
+}
+
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// ScentMapSchemaVersion
+// Identifies the layout of saved scent maps, versioned separately from run records.
+const ScentMapSchemaVersion = "marster-bot/scents/v1"
+
+type ScentRecord struct {
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Direction   string `json:"direction"`
+	Instruction string `json:"instruction,omitempty"`
+}
+
+// ScentMapRecord
+// A grid's size and the scents left on it, saved so a campaign can be continued or shared.
+type ScentMapRecord struct {
+	Schema string        `json:"schema"`
+	MaxX   int           `json:"max_x"`
+	MaxY   int           `json:"max_y"`
+	Scents []ScentRecord `json:"scents"`
+}
+
+// WriteScentMap
+// Writes a scent map as an indented JSON document.
+func WriteScentMap(writer io.Writer, scentMap ScentMapRecord) error {
+	scentMap.Schema = ScentMapSchemaVersion
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(scentMap)
+}
+
+// ReadScentMap
+// Reads a scent map written by WriteScentMap, rejecting documents from another schema.
+func ReadScentMap(reader io.Reader) (ScentMapRecord, error) {
+	var scentMap ScentMapRecord
+	if err := json.NewDecoder(reader).Decode(&scentMap); err != nil {
+		return scentMap, fmt.Errorf("invalid scent map: %w", err)
+	}
+	if scentMap.Schema != ScentMapSchemaVersion {
+		return scentMap, fmt.Errorf("unsupported scent map schema '%s': expected '%s'", scentMap.Schema, ScentMapSchemaVersion)
+	}
+	return scentMap, nil
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRecordWriter(t *testing.T) {
+	grid := GridRecord{MaxX: 5, MaxY: 3, Obstacles: []PointRecord{}, Scents: []PointRecord{}}
+	rover := RoverRecord{
+		Number:       1,
+		Start:        PoseRecord{X: 3, Y: 2, Direction: "N"},
+		Instructions: []string{"F", "F"},
+		Final:        PoseRecord{X: 3, Y: 3, Direction: "N"},
+		Lost:         true,
+		ScentAdded:   &PointRecord{X: 3, Y: 3},
+	}
+
+	t.Run("json writes a single versioned document on close", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer, err := NewRecordWriter("json", &buffer)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		writer.WriteGrid(grid)
+		writer.WriteRover(rover)
+		if buffer.Len() != 0 {
+			t.Error("Expected nothing to be written before close")
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var document struct {
+			Schema string        `json:"schema"`
+			Grid   GridRecord    `json:"grid"`
+			Rovers []RoverRecord `json:"rovers"`
+		}
+		if err := json.Unmarshal(buffer.Bytes(), &document); err != nil {
+			t.Fatalf("Expected valid JSON, got: %v", err)
+		}
+		if document.Schema != SchemaVersion {
+			t.Errorf("Expected schema %q, got %q", SchemaVersion, document.Schema)
+		}
+		if document.Grid.MaxX != 5 || len(document.Rovers) != 1 || !document.Rovers[0].Lost {
+			t.Errorf("Unexpected document: %+v", document)
+		}
+	})
+
+	t.Run("ndjson streams one typed record per line", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer, err := NewRecordWriter("ndjson", &buffer)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		writer.WriteGrid(grid)
+		writer.WriteRover(rover)
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 lines, got %d", len(lines))
+		}
+
+		for i, wantType := range []string{"grid", "rover"} {
+			var line map[string]any
+			if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+				t.Fatalf("Expected valid JSON on line %d, got: %v", i+1, err)
+			}
+			if line["type"] != wantType || line["schema"] != SchemaVersion {
+				t.Errorf("Line %d: expected type %q with schema, got %v", i+1, wantType, line)
+			}
+		}
+		if !strings.Contains(lines[1], `"scent_added":{"x":3,"y":3}`) {
+			t.Errorf("Expected rover line to include the scent, got %s", lines[1])
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		if _, err := NewRecordWriter("xml", &bytes.Buffer{}); err == nil {
+			t.Error("Expected error for unknown format")
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ScenarioSchemaVersion
+// Identifies the layout of scenario files, versioned separately from run records.
+const ScenarioSchemaVersion = "marster-bot/scenario/v1"
+
+// ScenarioRecord
+// A grid, the obstacles and scents it starts with, rovers to run on it one after another and where each should end.
+// Policies left out keep the grid's defaults. ExpectedScents is only checked when given, and then must list every
+// scent on the grid once the rovers have run.
+type ScenarioRecord struct {
+	Schema          string                `json:"schema"`
+	Name            string                `json:"name,omitempty"`
+	MaxX            int                   `json:"max_x"`
+	MaxY            int                   `json:"max_y"`
+	EdgePolicy      string                `json:"edge_policy,omitempty"`
+	ObstaclePolicy  string                `json:"obstacle_policy,omitempty"`
+	CollisionPolicy string                `json:"collision_policy,omitempty"`
+	ScentMode       string                `json:"scent_mode,omitempty"`
+	Obstacles       []PointRecord         `json:"obstacles,omitempty"`
+	Scents          []ScentRecord         `json:"scents,omitempty"`
+	Rovers          []ScenarioRoverRecord `json:"rovers"`
+	ExpectedScents  *[]ScentRecord        `json:"expected_scents,omitempty"`
+}
+
+// ScenarioRoverRecord
+// A rover's starting pose and instructions, and its expected outcome written the way 'run' prints it, e.g. '1 1 E'
+// or '3 3 N LOST'.
+type ScenarioRoverRecord struct {
+	Pose         string `json:"pose"`
+	Instructions string `json:"instructions"`
+	Expected     string `json:"expected"`
+}
+
+// ReadScenario
+// Reads a scenario file. Unknown fields are rejected so a misspelt expectation is not silently skipped.
+func ReadScenario(reader io.Reader) (ScenarioRecord, error) {
+	var scenario ScenarioRecord
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&scenario); err != nil {
+		return scenario, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if scenario.Schema != ScenarioSchemaVersion {
+		return scenario, fmt.Errorf("unsupported scenario schema '%s': expected '%s'", scenario.Schema, ScenarioSchemaVersion)
+	}
+	if len(scenario.Rovers) == 0 {
+		return scenario, fmt.Errorf("invalid scenario: no rovers")
+	}
+	return scenario, nil
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadScenario(t *testing.T) {
+	t.Run("Reads a scenario", func(t *testing.T) {
+		scenario, err := ReadScenario(strings.NewReader(`{
+			"schema": "` + ScenarioSchemaVersion + `",
+			"name": "Lost rover",
+			"max_x": 5, "max_y": 3,
+			"obstacles": [{"x": 2, "y": 2}],
+			"rovers": [{"pose": "3 2 N", "instructions": "FF", "expected": "3 3 N LOST"}],
+			"expected_scents": [{"x": 3, "y": 3, "direction": "N"}]
+		}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if scenario.Name != "Lost rover" || scenario.MaxX != 5 || len(scenario.Obstacles) != 1 {
+			t.Errorf("Expected the grid to be read, got %+v", scenario)
+		}
+		if len(scenario.Rovers) != 1 || scenario.Rovers[0].Expected != "3 3 N LOST" {
+			t.Errorf("Expected one rover ending 3 3 N LOST, got %+v", scenario.Rovers)
+		}
+		if scenario.ExpectedScents == nil || len(*scenario.ExpectedScents) != 1 {
+			t.Errorf("Expected one expected scent, got %v", scenario.ExpectedScents)
+		}
+	})
+
+	t.Run("Scents are only checked when given", func(t *testing.T) {
+		scenario, err := ReadScenario(strings.NewReader(`{"schema": "` + ScenarioSchemaVersion + `", "max_x": 5, "max_y": 3,
+			"rovers": [{"pose": "1 1 E", "instructions": "F", "expected": "2 1 E"}]}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if scenario.ExpectedScents != nil {
+			t.Errorf("Expected no scent expectation, got %v", *scenario.ExpectedScents)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{"Empty", "", "invalid scenario"},
+		{"Wrong schema", `{"schema": "marster-bot/v1"}`, "unsupported scenario schema 'marster-bot/v1'"},
+		{"Unknown field", `{"schema": "` + ScenarioSchemaVersion + `", "expect_scents": []}`, `unknown field "expect_scents"`},
+		{"No rovers", `{"schema": "` + ScenarioSchemaVersion + `", "max_x": 5, "max_y": 3}`, "no rovers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadScenario(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
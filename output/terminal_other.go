@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package output
+
+func terminalSize() (int, int, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TranscriptSchemaVersion
+// Identifies the layout of recorded session transcripts, versioned separately from run records.
+const TranscriptSchemaVersion = "marster-bot/transcript/v1"
+
+// SessionOptionsRecord
+// The grid options a session was started with, so a replay configures its grids the same way.
+type SessionOptionsRecord struct {
+	ObstaclePolicy  string   `json:"obstacle_policy"`
+	CollisionPolicy string   `json:"collision_policy"`
+	EdgePolicy      string   `json:"edge_policy,omitempty"`
+	ScentMode       string   `json:"scent_mode"`
+	Obstacles       []string `json:"obstacles"`
+	ScentsIn        string   `json:"scents_in,omitempty"`
+	Optimize        bool     `json:"optimize,omitempty"`
+}
+
+// SessionStateRecord
+// What an interactive session looks like between prompts: its grid, scents and the rovers run so far. Grid is nil
+// until the operator has entered one.
+type SessionStateRecord struct {
+	Grid   *GridRecord   `json:"grid"`
+	Scents []ScentRecord `json:"scents"`
+	Rovers []RoverRecord `json:"rovers"`
+}
+
+// TranscriptHeader
+// The first line of a transcript.
+type TranscriptHeader struct {
+	Schema  string               `json:"schema"`
+	Type    string               `json:"type"`
+	Options SessionOptionsRecord `json:"options"`
+}
+
+// TranscriptEntry
+// One answer typed at a prompt, and the session's state once the answer had been dealt with.
+type TranscriptEntry struct {
+	Type   string             `json:"type"`
+	Prompt string             `json:"prompt"`
+	Answer string             `json:"answer"`
+	State  SessionStateRecord `json:"state"`
+}
+
+// TranscriptWriter
+// Writes a transcript as JSON lines: a header followed by one entry per answer.
+type TranscriptWriter struct {
+	encoder *json.Encoder
+}
+
+func NewTranscriptWriter(writer io.Writer) *TranscriptWriter {
+	return &TranscriptWriter{encoder: json.NewEncoder(writer)}
+}
+
+func (w *TranscriptWriter) WriteHeader(options SessionOptionsRecord) error {
+	return w.encoder.Encode(TranscriptHeader{Schema: TranscriptSchemaVersion, Type: "session", Options: options})
+}
+
+func (w *TranscriptWriter) WriteEntry(entry TranscriptEntry) error {
+	entry.Type = "answer"
+	return w.encoder.Encode(entry)
+}
+
+// ReadTranscript
+// Reads a transcript written by TranscriptWriter, rejecting transcripts from another schema.
+func ReadTranscript(reader io.Reader) (TranscriptHeader, []TranscriptEntry, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 16<<20)
+
+	var header TranscriptHeader
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, fmt.Errorf("invalid transcript: empty")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, fmt.Errorf("invalid transcript header: %w", err)
+	}
+	if header.Schema != TranscriptSchemaVersion {
+		return header, nil, fmt.Errorf("unsupported transcript schema '%s': expected '%s'", header.Schema, TranscriptSchemaVersion)
+	}
+
+	var entries []TranscriptEntry
+	for line := 2; scanner.Scan(); line++ {
+		var entry TranscriptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return header, nil, fmt.Errorf("invalid transcript line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return header, entries, scanner.Err()
+}
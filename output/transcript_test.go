@@ -0,0 +1,76 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	options := SessionOptionsRecord{ObstaclePolicy: "stop", CollisionPolicy: "block", ScentMode: "exit", Obstacles: []string{"2,2"}}
+	grid := GridRecord{MaxX: 5, MaxY: 3, Obstacles: []PointRecord{{X: 2, Y: 2}}, Scents: []PointRecord{}}
+	entries := []TranscriptEntry{
+		{Prompt: "Enter grid upper-right coordinates (x,y): ", Answer: "5,3", State: SessionStateRecord{
+			Grid: &grid, Scents: []ScentRecord{}, Rovers: []RoverRecord{},
+		}},
+		{Prompt: "Enter rover position and direction: ", Answer: ":map", State: SessionStateRecord{
+			Grid: &grid, Scents: []ScentRecord{{X: 3, Y: 3, Direction: "N"}}, Rovers: []RoverRecord{},
+		}},
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		var buffer bytes.Buffer
+		writer := NewTranscriptWriter(&buffer)
+		if err := writer.WriteHeader(options); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, entry := range entries {
+			if err := writer.WriteEntry(entry); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(lines))
+		}
+
+		header, read, err := ReadTranscript(&buffer)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if header.Schema != TranscriptSchemaVersion || header.Type != "session" {
+			t.Errorf("Expected a %s session header, got %+v", TranscriptSchemaVersion, header)
+		}
+		if header.Options.ObstaclePolicy != "stop" || len(header.Options.Obstacles) != 1 {
+			t.Errorf("Expected the options to round trip, got %+v", header.Options)
+		}
+		if len(read) != len(entries) {
+			t.Fatalf("Expected %d entries, got %d", len(entries), len(read))
+		}
+		for i, entry := range read {
+			if entry.Type != "answer" || entry.Answer != entries[i].Answer || entry.Prompt != entries[i].Prompt {
+				t.Errorf("Entry %d: expected answer %q at %q, got %+v", i+1, entries[i].Answer, entries[i].Prompt, entry)
+			}
+		}
+		if len(read[1].State.Scents) != 1 || read[1].State.Grid.MaxX != 5 {
+			t.Errorf("Expected the state to round trip, got %+v", read[1].State)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Empty transcript", ""},
+		{"Wrong schema", `{"schema":"marster-bot/v1","type":"session"}`},
+		{"Invalid entry", `{"schema":"` + TranscriptSchemaVersion + `","type":"session"}` + "\n{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ReadTranscript(strings.NewReader(tt.input)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
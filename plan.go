@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+// planRoute
+// Prints the shortest safe instruction string from the start pose to the target, once the mission's rovers have run,
+// or says so when the start pose is already at the target.
+func planRoute(options *gridOptions, reader io.Reader, name string, from string, to string, writer io.Writer) error {
+	grid, err := runToGrid(options, reader, name)
+	if err != nil {
+		return err
+	}
+
+	rover, err := input.ParseRover(from, grid)
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+	position, direction, err := input.ParseTarget(to, grid)
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+
+	target := engine.Target{Position: position, Direction: direction}
+	instructions, err := engine.Plan(grid, rover, target)
+	if err != nil {
+		return err
+	}
+
+	if len(instructions) == 0 {
+		fmt.Fprintf(writer, "already at target %s\n", target)
+		return nil
+	}
+	fmt.Fprintln(writer, engine.Program(instructions))
+	return nil
+}
+
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "plan",
+		Usage:     "Print the shortest safe instruction string from a start pose to a target cell or pose",
+		ArgsUsage: "[mission-file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "from",
+				Usage:    "Start pose as 'x y D'",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "to",
+				Usage:    "Target cell as 'x y', or pose as 'x y D'",
+				Required: true,
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			options, err := gridOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			reader, name, err := openMission(c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			return planRoute(options, reader, name, c.String("from"), c.String("to"), os.Stdout)
+		},
+	}
+}
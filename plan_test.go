@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlanRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{"Prints the route", "1 1 N", "1 3", "F2\n"},
+		{"Already at the target cell", "1 1 N", "1 1", "already at target (1,1)\n"},
+		{"Already at the target pose", "1 1 N", "1 1 N", "already at target (1,1) N\n"},
+		{"Turns to face the target pose", "1 1 N", "1 1 E", "R\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := planRoute(&gridOptions{}, strings.NewReader("5 3\n"), "missions.txt", tt.from, tt.to, &out)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
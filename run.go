@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+// openMission
+// Opens the mission file named on the command line, falling back to stdin when it is piped.
+func openMission(path string) (io.ReadCloser, string, error) {
+	if path == "" || path == "-" {
+		info, err := os.Stdin.Stat()
+		if err != nil {
+			return nil, "", err
+		}
+		if path == "" && info.Mode()&os.ModeCharDevice != 0 {
+			return nil, "", fmt.Errorf("no mission file given and nothing piped to stdin")
+		}
+		return io.NopCloser(os.Stdin), "<stdin>", nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, path, nil
+}
+
+// runToGrid
+// Runs every rover in the mission quietly, one after another, and returns the grid they leave behind with its
+// scents and parked rovers, for commands that work against the state of a mission rather than reporting on it.
+func runToGrid(options *gridOptions, reader io.Reader, name string) (*mars.Grid, error) {
+	mission, err := input.ParseMission(reader, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := options.applyMission(mission); err != nil {
+		return nil, err
+	}
+
+	simulation := engine.NewSimulation(mission.Grid)
+	for _, rover := range mission.Rovers {
+		simulation.AddRover(rover.Rover, rover.Instructions)
+	}
+	for _, result := range simulation.Run() {
+		if result.Err != nil && !result.Lost && result.Obstacle == nil && result.Collision == nil && result.Loop == nil {
+			return nil, fmt.Errorf("rover #%d: %w", result.Number, result.Err)
+		}
+	}
+
+	return mission.Grid, nil
+}
+
+// concurrency
+// How a mission's rovers are run: one after another, all at once with a precedence for shared scents, or in
+// lock-step ticks.
+type concurrency struct {
+	enabled    bool
+	workers    int
+	precedence engine.Precedence
+	lockstep   bool
+}
+
+func runMission(console *output.Console, options *gridOptions, reader io.Reader, name string, format string, showMap bool, optimize bool, concurrent concurrency, writer io.Writer) error {
+	mission, err := input.ParseMission(reader, name)
+	if err != nil {
+		return err
+	}
+
+	if optimize {
+		for i, rover := range mission.Rovers {
+			optimization := engine.Optimize(rover.Instructions)
+			console.Info("Rover #%d optimized: %s", i+1, optimization)
+			mission.Rovers[i].Instructions = optimization.Instructions
+		}
+	}
+
+	if err := options.applyMission(mission); err != nil {
+		return err
+	}
+
+	var records output.RecordWriter
+	if format != "text" {
+		records, err = output.NewRecordWriter(format, writer)
+		if err != nil {
+			return err
+		}
+		if err := records.WriteGrid(engine.GridRecord(mission.Grid)); err != nil {
+			return err
+		}
+	}
+
+	simulation := engine.NewSimulation(mission.Grid)
+	mission.Grid.Subscribe(engine.NewConsoleListener(console))
+
+	report := func(results []*engine.RoverResult) error {
+		for _, result := range results {
+			if records != nil {
+				if err := records.WriteRover(result.Record()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if result.Err != nil && !result.Lost && result.Obstacle == nil && result.Collision == nil && result.Loop == nil {
+				return fmt.Errorf("rover #%d: %w", result.Number, result.Err)
+			}
+			fmt.Fprintln(writer, result)
+		}
+
+		if showMap {
+			console.Map(simulation.Map(), output.MapView{})
+		}
+		return nil
+	}
+
+	switch {
+	case concurrent.lockstep:
+		for _, rover := range mission.Rovers {
+			simulation.AddRover(rover.Rover, rover.Instructions)
+		}
+		results, ticks, err := simulation.RunLockstep()
+		if err != nil {
+			return err
+		}
+		for _, tick := range ticks {
+			if records != nil {
+				if err := records.WriteTick(tick.Record()); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintln(writer, tick)
+		}
+		if err := report(results); err != nil {
+			return err
+		}
+	case concurrent.enabled:
+		for _, rover := range mission.Rovers {
+			simulation.AddRover(rover.Rover, rover.Instructions)
+		}
+		if err := report(simulation.RunConcurrent(concurrent.workers, concurrent.precedence)); err != nil {
+			return err
+		}
+	default:
+		for _, rover := range mission.Rovers {
+			simulation.AddRover(rover.Rover, rover.Instructions)
+			if err := report(simulation.Run()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := options.save(mission.Grid); err != nil {
+		return err
+	}
+
+	if records != nil {
+		return records.Close()
+	}
+
+	return nil
+}
+
+func runCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "run",
+		Usage:     "Run every rover in a mission file (or stdin) and print their final positions",
+		ArgsUsage: "[mission-file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format: text, json, or ndjson to stream one record per line",
+				Value: "text",
+				Validator: func(format string) error {
+					if format != "text" && format != "json" && format != "ndjson" {
+						return fmt.Errorf("must be text, json or ndjson")
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "concurrent",
+				Usage: "Run every rover at once; rovers see scents from before the batch but not each other's, so results can differ from a run in order",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "Number of rovers to run in parallel with --concurrent (default: one per CPU)",
+			},
+			&cli.BoolFlag{
+				Name:  "lockstep",
+				Usage: "Run rovers in lock-step ticks, one action each per tick, and print a timeline of every pose",
+			},
+			&cli.Uint64Flag{
+				Name:  "seed",
+				Usage: "Shuffle which concurrent rover is credited with a shared scent, reproducibly (default: file order)",
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			options, err := gridOptionsFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			reader, name, err := openMission(c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+
+			format := c.String("format")
+			console := output.NewConsole(*bufio.NewReader(os.Stdin), c.Bool("debug"))
+			if format != "text" {
+				// Keep stdout clean for the records
+				console.SetWriter(os.Stderr)
+			}
+			if c.Bool("lockstep") && c.Bool("concurrent") {
+				return fmt.Errorf("--lockstep and --concurrent cannot be used together")
+			}
+
+			concurrent := concurrency{
+				enabled:    c.Bool("concurrent"),
+				workers:    int(c.Int("workers")),
+				precedence: engine.Precedence{Seeded: c.IsSet("seed"), Seed: c.Uint64("seed")},
+				lockstep:   c.Bool("lockstep"),
+			}
+			return runMission(console, options, reader, name, format, c.Bool("map"), c.Bool("optimize"), concurrent, os.Stdout)
+		},
+	}
+}
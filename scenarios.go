@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"marster-bot/engine"
+	"marster-bot/output"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// scenarioFiles
+// Expands the paths given on the command line into scenario files: files are taken as they are, and directories are
+// searched for '.json' files, in name order.
+func scenarioFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(file), ".json") {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// runScenarioFile
+// Reads and runs one scenario file, naming it after the file when it has no name of its own.
+func runScenarioFile(path string) (*engine.ScenarioReport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scenario, err := output.ReadScenario(file)
+	if err != nil {
+		return nil, err
+	}
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return engine.RunScenario(scenario)
+}
+
+// runScenarios
+// Runs every scenario file, printing PASS or FAIL for each with what differed, and returns an error if any failed
+// or could not be run.
+func runScenarios(files []string, writer io.Writer) error {
+	passed, failed := 0, 0
+	for _, path := range files {
+		report, err := runScenarioFile(path)
+		if err != nil {
+			failed++
+			fmt.Fprintf(writer, "ERROR %s: %v\n", path, err)
+			continue
+		}
+		if report.Passed() {
+			passed++
+			fmt.Fprintf(writer, "PASS  %s (%s)\n", report.Name, path)
+			continue
+		}
+
+		failed++
+		fmt.Fprintf(writer, "FAIL  %s (%s)\n", report.Name, path)
+		for _, mismatch := range report.Mismatches {
+			fmt.Fprintf(writer, "      %s:\n", mismatch.Subject)
+			for _, expected := range mismatch.Expected {
+				fmt.Fprintf(writer, "        - %s\n", expected)
+			}
+			for _, got := range mismatch.Got {
+				fmt.Fprintf(writer, "        + %s\n", got)
+			}
+		}
+	}
+
+	fmt.Fprintf(writer, "\n%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios failed", failed, len(files))
+	}
+	return nil
+}
+
+func testCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "test",
+		Usage:     "Run scenario files and check that every rover ends where the scenario expects",
+		ArgsUsage: "<scenario-file-or-directory>...",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.Args().Len() == 0 {
+				return fmt.Errorf("expected scenario files or directories")
+			}
+
+			files, err := scenarioFiles(c.Args().Slice())
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no scenario files found")
+			}
+
+			return runScenarios(files, os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"marster-bot/output"
+	"marster-bot/server"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve simulations over an HTTP/JSON API",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "Address to listen on",
+				Value: "localhost:8080",
+			},
+		},
+		Action: func(ctx context.Context, c *cli.Command) error {
+			console := output.NewConsole(*bufio.NewReader(os.Stdin), c.Bool("debug"))
+
+			httpServer := &http.Server{
+				Addr:              c.String("addr"),
+				Handler:           server.New(),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			// Finish in-flight requests when interrupted
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				httpServer.Shutdown(shutdown)
+			}()
+
+			console.Info("Listening on http://%s", httpServer.Addr)
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// maxBodyBytes
+// Caps request bodies so a single client cannot exhaust memory.
+const maxBodyBytes = 1 << 20
+
+// session
+// A grid and the rovers added to it. Every session owns its own grid and is locked for the length of each request,
+// so requests against one session are serialised while different sessions run independently.
+type session struct {
+	mu         sync.Mutex
+	id         string
+	simulation *engine.Simulation
+}
+
+// Server
+// Serves the simulation over HTTP with JSON request and response bodies.
+type Server struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	mux      *http.ServeMux
+}
+
+func New() *Server {
+	s := &Server{
+		sessions: make(map[string]*session),
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("POST /sessions", s.createSession)
+	s.mux.HandleFunc("GET /sessions/{id}", s.withSession(s.getSession))
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.deleteSession)
+	s.mux.HandleFunc("POST /sessions/{id}/rovers", s.withSession(s.addRover))
+	s.mux.HandleFunc("GET /sessions/{id}/rovers", s.withSession(s.listRovers))
+	s.mux.HandleFunc("GET /sessions/{id}/rovers/{number}", s.withSession(s.getRover))
+	s.mux.HandleFunc("POST /sessions/{id}/run", s.withSession(s.run))
+	s.mux.HandleFunc("GET /sessions/{id}/scents", s.withSession(s.getScents))
+	s.mux.HandleFunc("POST /sessions/{id}/plan", s.withSession(s.plan))
+	s.mux.HandleFunc("POST /sessions/{id}/check", s.withSession(s.check))
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type createSessionRequest struct {
+	MaxX            int                  `json:"max_x"`
+	MaxY            int                  `json:"max_y"`
+	ObstaclePolicy  string               `json:"obstacle_policy"`
+	CollisionPolicy string               `json:"collision_policy"`
+	EdgePolicy      string               `json:"edge_policy"`
+	ScentMode       string               `json:"scent_mode"`
+	Obstacles       []output.PointRecord `json:"obstacles"`
+}
+
+type sessionResponse struct {
+	Schema  string            `json:"schema"`
+	ID      string            `json:"id"`
+	Grid    output.GridRecord `json:"grid"`
+	Rovers  int               `json:"rovers"`
+	Pending int               `json:"pending"`
+}
+
+type addRoverRequest struct {
+	Pose         string `json:"pose"`
+	Instructions string `json:"instructions"`
+}
+
+type addRoverResponse struct {
+	Number  int `json:"number"`
+	Pending int `json:"pending"`
+}
+
+type roversResponse struct {
+	Schema  string               `json:"schema"`
+	Rovers  []output.RoverRecord `json:"rovers"`
+	Pending int                  `json:"pending"`
+}
+
+type planRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type planResponse struct {
+	Instructions string `json:"instructions"`
+}
+
+type checkRequest struct {
+	From         string `json:"from"`
+	Instructions string `json:"instructions"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var request createSessionRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	grid, err := newGrid(request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sess := &session{id: id, simulation: engine.NewSimulation(grid)}
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	w.Header().Set("Location", "/sessions/"+id)
+	writeJSON(w, http.StatusCreated, describe(sess))
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	_, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session '%s'", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request, sess *session) {
+	writeJSON(w, http.StatusOK, describe(sess))
+}
+
+func (s *Server) addRover(w http.ResponseWriter, r *http.Request, sess *session) {
+	var request addRoverRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	rover, err := input.ParseRover(request.Pose, sess.simulation.Grid)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	instructions, err := input.ParseInstructions(request.Instructions)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	number := sess.simulation.AddRover(rover, instructions)
+	writeJSON(w, http.StatusCreated, addRoverResponse{Number: number, Pending: sess.simulation.Pending()})
+}
+
+func (s *Server) listRovers(w http.ResponseWriter, r *http.Request, sess *session) {
+	writeJSON(w, http.StatusOK, roversResponse{
+		Schema:  output.SchemaVersion,
+		Rovers:  records(sess.simulation.Results()),
+		Pending: sess.simulation.Pending(),
+	})
+}
+
+func (s *Server) getRover(w http.ResponseWriter, r *http.Request, sess *session) {
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid rover number '%s'", r.PathValue("number")))
+		return
+	}
+
+	for _, result := range sess.simulation.Results() {
+		if result.Number == number {
+			writeJSON(w, http.StatusOK, result.Record())
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("rover #%d has not run in session '%s'", number, sess.id))
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request, sess *session) {
+	writeJSON(w, http.StatusOK, roversResponse{
+		Schema:  output.SchemaVersion,
+		Rovers:  records(sess.simulation.Run()),
+		Pending: sess.simulation.Pending(),
+	})
+}
+
+func (s *Server) getScents(w http.ResponseWriter, r *http.Request, sess *session) {
+	scentMap := engine.ScentMap(sess.simulation.Grid)
+	scentMap.Schema = output.ScentMapSchemaVersion
+	writeJSON(w, http.StatusOK, scentMap)
+}
+
+func (s *Server) plan(w http.ResponseWriter, r *http.Request, sess *session) {
+	var request planRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	grid := sess.simulation.Grid
+	rover, err := input.ParseRover(request.From, grid)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	position, direction, err := input.ParseTarget(request.To, grid)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	instructions, err := engine.Plan(grid, rover, engine.Target{Position: position, Direction: direction})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, engine.ErrNoRoute) {
+			status = http.StatusUnprocessableEntity
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, planResponse{Instructions: engine.Program(instructions)})
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request, sess *session) {
+	var request checkRequest
+	if !decode(w, r, &request) {
+		return
+	}
+
+	rover, err := input.ParseRover(request.From, sess.simulation.Grid)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	instructions, err := input.ParseInstructions(request.Instructions)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, engine.Check(sess.simulation.Grid, rover, instructions).Record())
+}
+
+// withSession
+// Looks up the session named in the path and holds its lock while the handler runs.
+func (s *Server) withSession(handler func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		s.mu.Lock()
+		sess, ok := s.sessions[id]
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no session '%s'", id))
+			return
+		}
+
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		handler(w, r, sess)
+	}
+}
+
+// newGrid
+// Builds a session's grid from the request, applying any policies and obstacles it names.
+func newGrid(request createSessionRequest) (*mars.Grid, error) {
+	if request.MaxX < 1 || request.MaxY < 1 || request.MaxX > math.MaxInt8 || request.MaxY > math.MaxInt8 {
+		return nil, fmt.Errorf("grid must be between 1,1 and %d,%d (got %d,%d)",
+			math.MaxInt8, math.MaxInt8, request.MaxX, request.MaxY)
+	}
+	grid := mars.NewGrid(uint8(request.MaxX), uint8(request.MaxY))
+
+	var ok bool
+	if request.ObstaclePolicy != "" {
+		if grid.ObstaclePolicy, ok = mars.ObstaclePolicyFromName(request.ObstaclePolicy); !ok {
+			return nil, fmt.Errorf("invalid obstacle_policy '%s': must be skip or stop", request.ObstaclePolicy)
+		}
+	}
+	if request.CollisionPolicy != "" {
+		if grid.CollisionPolicy, ok = mars.CollisionPolicyFromName(request.CollisionPolicy); !ok {
+			return nil, fmt.Errorf("invalid collision_policy '%s': must be block, abort or allow", request.CollisionPolicy)
+		}
+	}
+	if request.EdgePolicy != "" {
+		if err := input.ParseEdge(request.EdgePolicy, grid); err != nil {
+			return nil, err
+		}
+	}
+	if request.ScentMode != "" {
+		if grid.ScentMode, ok = mars.ScentModeFromName(request.ScentMode); !ok {
+			return nil, fmt.Errorf("invalid scent_mode '%s': must be exit or position", request.ScentMode)
+		}
+	}
+
+	for _, obstacle := range request.Obstacles {
+		if err := input.ParseObstacle(fmt.Sprintf("%d %d", obstacle.X, obstacle.Y), grid); err != nil {
+			return nil, err
+		}
+	}
+
+	return grid, nil
+}
+
+func describe(sess *session) sessionResponse {
+	return sessionResponse{
+		Schema:  output.SchemaVersion,
+		ID:      sess.id,
+		Grid:    engine.GridRecord(sess.simulation.Grid),
+		Rovers:  len(sess.simulation.Results()),
+		Pending: sess.simulation.Pending(),
+	}
+}
+
+func records(results []*engine.RoverResult) []output.RoverRecord {
+	rovers := make([]output.RoverRecord, 0, len(results))
+	for _, result := range results {
+		rovers = append(rovers, result.Record())
+	}
+	return rovers
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to create session id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// decode
+// Reads a JSON request body, writing a 400 response and returning false if it is not valid.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"marster-bot/output"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func request(t *testing.T, handler http.Handler, method, path, body string, v any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if v != nil {
+		if err := json.NewDecoder(bytes.NewReader(recorder.Body.Bytes())).Decode(v); err != nil {
+			t.Fatalf("Failed to decode %s %s response %q: %v", method, path, recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code
+}
+
+func createSession(t *testing.T, handler http.Handler, body string) string {
+	t.Helper()
+
+	var created sessionResponse
+	if status := request(t, handler, "POST", "/sessions", body, &created); status != http.StatusCreated {
+		t.Fatalf("Expected 201 creating session, got %d", status)
+	}
+	return created.ID
+}
+
+func TestServer(t *testing.T) {
+	t.Run("Runs rovers and reports poses and scents", func(t *testing.T) {
+		srv := New()
+		id := createSession(t, srv, `{"max_x":5,"max_y":3,"obstacles":[{"x":0,"y":3}]}`)
+
+		var added addRoverResponse
+		status := request(t, srv, "POST", "/sessions/"+id+"/rovers", `{"pose":"1 1 E","instructions":"RFRFRFRF"}`, &added)
+		if status != http.StatusCreated || added.Number != 1 || added.Pending != 1 {
+			t.Fatalf("Expected rover #1 queued, got %d %+v", status, added)
+		}
+		request(t, srv, "POST", "/sessions/"+id+"/rovers", `{"pose":"3 2 N","instructions":"FRRFLLFFRRFLL"}`, nil)
+
+		var ran roversResponse
+		if status := request(t, srv, "POST", "/sessions/"+id+"/run", "", &ran); status != http.StatusOK {
+			t.Fatalf("Expected 200 running rovers, got %d", status)
+		}
+		if len(ran.Rovers) != 2 || ran.Pending != 0 {
+			t.Fatalf("Expected 2 rovers run, got %+v", ran)
+		}
+		if final := ran.Rovers[1].Final; final.X != 3 || final.Y != 3 || !ran.Rovers[1].Lost {
+			t.Errorf("Expected rover #2 lost at (3,3), got %+v lost=%v", final, ran.Rovers[1].Lost)
+		}
+
+		var rover output.RoverRecord
+		if status := request(t, srv, "GET", "/sessions/"+id+"/rovers/1", "", &rover); status != http.StatusOK {
+			t.Fatalf("Expected 200 fetching rover #1, got %d", status)
+		}
+		if rover.Final.X != 1 || rover.Final.Y != 1 || rover.Final.Direction != "E" {
+			t.Errorf("Expected rover #1 at 1 1 E, got %+v", rover.Final)
+		}
+
+		var scents output.ScentMapRecord
+		request(t, srv, "GET", "/sessions/"+id+"/scents", "", &scents)
+		if len(scents.Scents) != 1 || scents.Scents[0] != (output.ScentRecord{X: 3, Y: 3, Direction: "N", Instruction: "F"}) {
+			t.Errorf("Expected a scent at (3,3) heading N, got %+v", scents.Scents)
+		}
+
+		var session sessionResponse
+		request(t, srv, "GET", "/sessions/"+id, "", &session)
+		if session.Rovers != 2 || len(session.Grid.Obstacles) != 1 {
+			t.Errorf("Expected 2 rovers and 1 obstacle, got %+v", session)
+		}
+	})
+
+	t.Run("Sessions are isolated", func(t *testing.T) {
+		srv := New()
+		first := createSession(t, srv, `{"max_x":5,"max_y":3}`)
+		second := createSession(t, srv, `{"max_x":5,"max_y":3}`)
+
+		request(t, srv, "POST", "/sessions/"+first+"/rovers", `{"pose":"3 3 N","instructions":"F"}`, nil)
+		request(t, srv, "POST", "/sessions/"+first+"/run", "", nil)
+
+		var scents output.ScentMapRecord
+		request(t, srv, "GET", "/sessions/"+second+"/scents", "", &scents)
+		if len(scents.Scents) != 0 {
+			t.Errorf("Expected no scents to leak into another session, got %+v", scents.Scents)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		errMsg string
+	}{
+		{"Unknown session", "GET", "/sessions/missing", "", http.StatusNotFound, "no session 'missing'"},
+		{"Grid too small", "POST", "/sessions", `{"max_x":0,"max_y":3}`, http.StatusBadRequest, "grid must be between"},
+		{"Grid too large", "POST", "/sessions", `{"max_x":5,"max_y":256}`, http.StatusBadRequest, "grid must be between"},
+		{"Negative grid", "POST", "/sessions", `{"max_x":-1,"max_y":3}`, http.StatusBadRequest, "grid must be between"},
+		{"Unknown policy", "POST", "/sessions", `{"max_x":5,"max_y":3,"edge_policy":"sticky"}`, http.StatusBadRequest, "invalid edge 'sticky'"},
+		{"Unknown field", "POST", "/sessions", `{"max_x":5,"max_y":3,"depth":2}`, http.StatusBadRequest, "unknown field"},
+		{"Obstacle outside grid", "POST", "/sessions", `{"max_x":5,"max_y":3,"obstacles":[{"x":9,"y":0}]}`, http.StatusBadRequest, "outside grid bounds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response errorResponse
+			status := request(t, New(), tt.method, tt.path, tt.body, &response)
+			if status != tt.status || !strings.Contains(response.Error, tt.errMsg) {
+				t.Errorf("Expected %d with error containing %q, got %d %q", tt.status, tt.errMsg, status, response.Error)
+			}
+		})
+	}
+
+	t.Run("Rejects invalid rovers", func(t *testing.T) {
+		srv := New()
+		id := createSession(t, srv, `{"max_x":5,"max_y":3}`)
+
+		var response errorResponse
+		status := request(t, srv, "POST", "/sessions/"+id+"/rovers", `{"pose":"1 1 E","instructions":"FX"}`, &response)
+		if status != http.StatusBadRequest || !strings.Contains(response.Error, "invalid instruction 'X'") {
+			t.Errorf("Expected 400 for an invalid instruction, got %d %q", status, response.Error)
+		}
+	})
+}
+
+func TestServerPlan(t *testing.T) {
+	srv := New()
+	id := createSession(t, srv, `{"max_x":5,"max_y":3,"obstacles":[{"x":1,"y":2}]}`)
+	request(t, srv, "POST", "/sessions/"+id+"/rovers", `{"pose":"3 3 N","instructions":"F"}`, nil)
+	request(t, srv, "POST", "/sessions/"+id+"/run", "", nil)
+
+	var planned planResponse
+	status := request(t, srv, "POST", "/sessions/"+id+"/plan", `{"from":"1 1 N","to":"1 3 N"}`, &planned)
+	if status != http.StatusOK || planned.Instructions != "LFLB2LFL" {
+		t.Errorf("Expected a route around the obstacle, got %d %q", status, planned.Instructions)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		errMsg string
+	}{
+		{"Invalid start", `{"from":"1 1","to":"3 3"}`, http.StatusBadRequest, "expected format 'x y D'"},
+		{"Target on an obstacle", `{"from":"1 1 N","to":"1 2"}`, http.StatusBadRequest, "blocked by an obstacle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var response errorResponse
+			status := request(t, srv, "POST", "/sessions/"+id+"/plan", tt.body, &response)
+			if status != tt.status || !strings.Contains(response.Error, tt.errMsg) {
+				t.Errorf("Expected %d with error containing %q, got %d %q", tt.status, tt.errMsg, status, response.Error)
+			}
+		})
+	}
+
+	t.Run("No safe route", func(t *testing.T) {
+		boxed := createSession(t, srv, `{"max_x":2,"max_y":2,"obstacles":[{"x":1,"y":0},{"x":0,"y":1}]}`)
+
+		var response errorResponse
+		status := request(t, srv, "POST", "/sessions/"+boxed+"/plan", `{"from":"0 0 N","to":"2 2"}`, &response)
+		if status != http.StatusUnprocessableEntity || !strings.Contains(response.Error, "no safe route") {
+			t.Errorf("Expected 422 with no safe route, got %d %q", status, response.Error)
+		}
+	})
+}
+
+func TestServerCheck(t *testing.T) {
+	srv := New()
+	id := createSession(t, srv, `{"max_x":5,"max_y":3}`)
+	request(t, srv, "POST", "/sessions/"+id+"/rovers", `{"pose":"3 3 N","instructions":"F"}`, nil)
+	request(t, srv, "POST", "/sessions/"+id+"/run", "", nil)
+
+	var checked output.CheckRecord
+	status := request(t, srv, "POST", "/sessions/"+id+"/check", `{"from":"3 3 N","instructions":"FRFFF"}`, &checked)
+	if status != http.StatusOK || !checked.Lost || checked.LostAt == nil || *checked.LostAt != 4 {
+		t.Fatalf("Expected the rover to be lost at instruction 4, got %d %+v", status, checked)
+	}
+	if len(checked.Ignored) != 1 || checked.Ignored[0].Index != 0 {
+		t.Errorf("Expected the first move to be ignored, got %+v", checked.Ignored)
+	}
+
+	var scents output.ScentMapRecord
+	request(t, srv, "GET", "/sessions/"+id+"/scents", "", &scents)
+	if len(scents.Scents) != 1 {
+		t.Errorf("Expected checking not to leave a scent, got %+v", scents.Scents)
+	}
+}
+
+func TestServerConcurrency(t *testing.T) {
+	srv := New()
+	shared := createSession(t, srv, `{"max_x":10,"max_y":10}`)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			own := createSession(t, srv, `{"max_x":10,"max_y":10}`)
+			body := fmt.Sprintf(`{"pose":"%d 10 N","instructions":"F"}`, i%10)
+			for _, id := range []string{own, shared} {
+				request(t, srv, "POST", "/sessions/"+id+"/rovers", body, nil)
+				request(t, srv, "POST", "/sessions/"+id+"/run", "", nil)
+				request(t, srv, "GET", "/sessions/"+id+"/scents", "", nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var rovers roversResponse
+	request(t, srv, "GET", "/sessions/"+shared+"/rovers", "", &rovers)
+	if len(rovers.Rovers) != 20 || rovers.Pending != 0 {
+		t.Errorf("Expected all 20 rovers to run in the shared session, got %d (%d pending)", len(rovers.Rovers), rovers.Pending)
+	}
+
+	var scents output.ScentMapRecord
+	request(t, srv, "GET", "/sessions/"+shared+"/scents", "", &scents)
+	if len(scents.Scents) != 10 {
+		t.Errorf("Expected 10 scents along the north edge, got %d", len(scents.Scents))
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"marster-bot/engine"
+	"marster-bot/input"
+	"marster-bot/mars"
+	"marster-bot/output"
+	"os"
+	"sort"
+	"strings"
+)
+
+// errNoGrid
+// Returned by meta-commands that need a grid when the operator has not entered one yet.
+var errNoGrid = errors.New("no grid yet, enter the grid size first")
+
+// session
+// The interactive session's grid, simulation and history, and the meta-commands that inspect and control them.
+// The history is nil until the grid has been entered.
+type session struct {
+	console   *output.Console
+	options   *gridOptions
+	history   *engine.History
+	macros    input.Macros // kept when another session is loaded
+	replaying bool         // set while a transcript is replayed, so ':save' does not write files
+	optimize  bool         // set by --optimize, so each rover's instructions are optimized before it runs
+}
+
+// start
+// Begins a session on the grid, narrating its events on the console.
+func (s *session) start(grid *mars.Grid) {
+	grid.Subscribe(engine.NewConsoleListener(s.console))
+	s.history = engine.NewHistory(engine.NewSimulation(grid))
+	if s.macros == nil {
+		s.macros = input.Macros{}
+	}
+}
+
+// state
+// Describes the session's grid, scents and rovers for a transcript. The grid is nil until one has been entered.
+func (s *session) state() output.SessionStateRecord {
+	record := output.SessionStateRecord{Scents: []output.ScentRecord{}, Rovers: []output.RoverRecord{}}
+	grid, err := s.grid()
+	if err != nil {
+		return record
+	}
+
+	gridRecord := engine.GridRecord(grid)
+	record.Grid = &gridRecord
+	record.Scents = engine.ScentMap(grid).Scents
+	for _, result := range s.history.Simulation.Results() {
+		record.Rovers = append(record.Rovers, result.Record())
+	}
+	return record
+}
+
+func (s *session) grid() (*mars.Grid, error) {
+	if s.history == nil {
+		return nil, errNoGrid
+	}
+	return s.history.Simulation.Grid, nil
+}
+
+// commands
+// Returns the meta-commands available at every prompt of the session.
+func (s *session) commands() []input.Command {
+	return []input.Command{
+		{Name: "map", Args: "[x y]", Usage: "Draw the grid, optionally centred on a cell", Run: s.showMap},
+		{Name: "undo", Args: "[rover]", Usage: "Roll back the last instruction, or the last rover", Run: s.undo},
+		{Name: "redo", Args: "[rover]", Usage: "Step forward again after an undo", Run: s.redo},
+		{Name: "history", Usage: "List every rover with a '|' before the next step to redo", Run: s.listHistory},
+		{Name: "scents", Usage: "List every scented cell and the headings it protects", Run: s.listScents},
+		{Name: "rovers", Usage: "List the rovers run so far with their final poses", Run: s.listRovers},
+		{Name: "macros", Usage: "List the macros defined so far and what they expand to", Run: s.listMacros},
+		{Name: "instructions", Usage: "List every kind of instruction and what it does", Run: s.listInstructions},
+		{Name: "save", Args: "<file>", Usage: "Save the grid and rovers as a mission file", Run: s.save},
+		{Name: "load", Args: "<file>", Usage: "Replace the session with the grid and rovers in a mission file", Run: s.load},
+		{Name: "debug", Args: "on|off", Usage: "Turn debug output on or off", Run: s.setDebug},
+		{Name: "reset", Usage: "Clear every scent and start a fresh undo history", Run: s.reset},
+	}
+}
+
+func (s *session) showMap(args []string) error {
+	grid, err := s.grid()
+	if err != nil {
+		return err
+	}
+
+	center, err := input.ParseMapCenter(args, grid)
+	if err != nil {
+		return err
+	}
+
+	view := output.MapView{}
+	if center != nil {
+		view.Center = &output.PointRecord{X: int(center.X), Y: int(center.Y)}
+	}
+	s.console.Map(s.history.Simulation.Map(), view)
+	return nil
+}
+
+func (s *session) undo(args []string) error {
+	rover, err := s.historyTarget(args)
+	if err != nil {
+		return err
+	}
+
+	if rover {
+		err = s.history.UndoRover()
+	} else {
+		err = s.history.Undo()
+	}
+	if err != nil {
+		return err
+	}
+	return s.listHistory(nil)
+}
+
+func (s *session) redo(args []string) error {
+	rover, err := s.historyTarget(args)
+	if err != nil {
+		return err
+	}
+
+	if rover {
+		err = s.history.RedoRover()
+	} else {
+		err = s.history.Redo()
+	}
+	if err != nil {
+		return err
+	}
+	return s.listHistory(nil)
+}
+
+// historyTarget
+// Reports whether ':undo' or ':redo' was asked to step over a whole rover rather than a single instruction.
+func (s *session) historyTarget(args []string) (bool, error) {
+	if _, err := s.grid(); err != nil {
+		return false, err
+	}
+
+	switch {
+	case len(args) == 0:
+		return false, nil
+	case len(args) == 1 && strings.ToLower(args[0]) == "rover":
+		return true, nil
+	default:
+		return false, fmt.Errorf("expected no arguments or 'rover'")
+	}
+}
+
+// listHistory
+// Lists every rover in the history, marking with '|' the next instruction that can be redone.
+func (s *session) listHistory(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected no arguments")
+	}
+	if _, err := s.grid(); err != nil {
+		return err
+	}
+
+	entries := s.history.Entries()
+	if len(entries) == 0 {
+		s.console.Info("History is empty")
+		return nil
+	}
+	s.console.Info("History ('|' marks the next instruction to redo):")
+	for _, entry := range entries {
+		s.console.Info("  %s", entry)
+	}
+	return nil
+}
+
+func (s *session) listScents(args []string) error {
+	grid, err := s.grid()
+	if err != nil {
+		return err
+	}
+
+	positions := grid.Scents()
+	if len(positions) == 0 {
+		s.console.Info("No scents")
+		return nil
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Y != positions[j].Y {
+			return positions[i].Y < positions[j].Y
+		}
+		return positions[i].X < positions[j].X
+	})
+
+	scents := grid.ExitScents()
+	for _, position := range positions {
+		var headings []string
+		for _, scent := range scents {
+			if scent.Position == position {
+				headings = append(headings, scent.Direction.String())
+			}
+		}
+		s.console.Info("  %s %s", position.String(), strings.Join(headings, " "))
+	}
+	return nil
+}
+
+func (s *session) listRovers(args []string) error {
+	if _, err := s.grid(); err != nil {
+		return err
+	}
+
+	results := s.history.Simulation.Results()
+	if len(results) == 0 {
+		s.console.Info("No rovers yet")
+		return nil
+	}
+	for _, result := range results {
+		s.console.Info("  #%d %d %d %s %s -> %s", result.Number, result.StartPosition.X, result.StartPosition.Y,
+			result.StartDirection, engine.Program(result.Instructions), result)
+	}
+	return nil
+}
+
+func (s *session) listMacros(args []string) error {
+	if len(s.macros) == 0 {
+		s.console.Info("No macros yet, define one with DEF NAME = ... at the instructions prompt")
+		return nil
+	}
+
+	names := make([]string, 0, len(s.macros))
+	for name := range s.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.console.Info("  @%s = %s", name, engine.Program(s.macros[name]))
+	}
+	return nil
+}
+
+func (s *session) listInstructions(args []string) error {
+	for _, kind := range mars.InstructionKinds() {
+		codes := "(syntax)"
+		if len(kind.Codes) > 0 {
+			codes = string(kind.Codes)
+		}
+		s.console.Info("  %-8s %-8s %s", kind.Name, codes, kind.Description)
+	}
+	return nil
+}
+
+func (s *session) save(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a file name")
+	}
+	if _, err := s.grid(); err != nil {
+		return err
+	}
+	if s.replaying {
+		s.console.Info("Skipped saving %s while replaying", args[0])
+		return nil
+	}
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := s.writeMission(file); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	s.console.Success("Saved %d rovers to %s", len(s.history.Simulation.Results()), args[0])
+	return nil
+}
+
+// writeMission
+// Writes the session in the mission file format, so it can be loaded again or run with the run command. Rovers
+// with no instructions cannot be written, as the format has no way to give an empty instruction line.
+func (s *session) writeMission(writer io.Writer) error {
+	grid := s.history.Simulation.Grid
+	lines := []string{fmt.Sprintf("%d %d", grid.XSize, grid.YSize)}
+	if grid.EdgePolicy != mars.EdgeLost {
+		lines = append(lines, "edge "+grid.EdgePolicy.String())
+	}
+	for _, obstacle := range engine.GridRecord(grid).Obstacles {
+		lines = append(lines, fmt.Sprintf("obstacle %d %d", obstacle.X, obstacle.Y))
+	}
+
+	for _, result := range s.history.Simulation.Results() {
+		if len(result.Instructions) == 0 {
+			s.console.Warning("Rover #%d has no instructions and was not saved", result.Number)
+			continue
+		}
+		lines = append(lines,
+			fmt.Sprintf("%d %d %s", result.StartPosition.X, result.StartPosition.Y, result.StartDirection),
+			engine.Program(result.Instructions))
+	}
+
+	_, err := fmt.Fprintln(writer, strings.Join(lines, "\n"))
+	return err
+}
+
+func (s *session) load(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a file name")
+	}
+	if _, err := s.grid(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mission, err := input.ParseMission(file, args[0])
+	if err != nil {
+		return err
+	}
+	if err := s.options.applyMission(mission); err != nil {
+		return err
+	}
+
+	s.start(mission.Grid)
+	for _, rover := range mission.Rovers {
+		s.history.Run(rover.Rover, rover.Instructions)
+	}
+
+	s.console.Success("Loaded a %dx%d grid and %d rovers from %s", mission.Grid.XSize, mission.Grid.YSize,
+		len(mission.Rovers), args[0])
+	return nil
+}
+
+func (s *session) setDebug(args []string) error {
+	if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+		return fmt.Errorf("expected 'on' or 'off'")
+	}
+
+	s.console.SetDebug(args[0] == "on")
+	s.console.Success("Debug output %s", args[0])
+	return nil
+}
+
+func (s *session) reset(args []string) error {
+	grid, err := s.grid()
+	if err != nil {
+		return err
+	}
+
+	grid.ClearScents()
+	s.history = engine.NewHistory(s.history.Simulation)
+	s.console.Success("Cleared every scent")
+	return nil
+}
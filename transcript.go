@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"marster-bot/output"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// transcriptRecorder
+// Writes every answer typed in a session to a transcript along with the state the answer left the session in. An
+// answer's state is taken when the next prompt is shown, or when the session ends, once the answer has been dealt with.
+type transcriptRecorder struct {
+	sess    *session
+	writer  *output.TranscriptWriter
+	pending *output.TranscriptEntry
+	err     error
+}
+
+func newTranscriptRecorder(sess *session, writer io.Writer) (*transcriptRecorder, error) {
+	recorder := &transcriptRecorder{sess: sess, writer: output.NewTranscriptWriter(writer)}
+	options := sess.options.record()
+	options.Optimize = sess.optimize
+	if err := recorder.writer.WriteHeader(options); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+func (r *transcriptRecorder) Prompting(text string) {
+	r.flush()
+}
+
+func (r *transcriptRecorder) Answered(text string, answer string) {
+	r.pending = &output.TranscriptEntry{Prompt: text, Answer: answer}
+}
+
+func (r *transcriptRecorder) flush() {
+	if r.pending == nil || r.err != nil {
+		return
+	}
+	r.pending.State = r.sess.state()
+	r.err = r.writer.WriteEntry(*r.pending)
+	r.pending = nil
+}
+
+// finish
+// Writes the last answer and returns the first error met while writing the transcript.
+func (r *transcriptRecorder) finish() error {
+	r.flush()
+	return r.err
+}
+
+// transcriptChecker
+// Follows a replayed session against its transcript, keeping the first place the two diverge: a prompt other than
+// the one the answer was recorded at, or a state other than the one recorded after an answer.
+type transcriptChecker struct {
+	sess       *session
+	entries    []output.TranscriptEntry
+	answered   int
+	divergence error
+}
+
+func (c *transcriptChecker) Prompting(text string) {
+	c.compareState()
+	if c.divergence == nil && c.answered < len(c.entries) && c.entries[c.answered].Prompt != text {
+		c.divergence = fmt.Errorf("answer %d (%q) was recorded at prompt %q but replayed at %q",
+			c.answered+1, c.entries[c.answered].Answer, c.entries[c.answered].Prompt, text)
+	}
+}
+
+func (c *transcriptChecker) Answered(text string, answer string) {
+	c.answered++
+}
+
+// compareState
+// Compares the session's state with the state recorded after the last answer.
+func (c *transcriptChecker) compareState() {
+	if c.divergence != nil || c.answered == 0 || c.answered > len(c.entries) {
+		return
+	}
+
+	entry := c.entries[c.answered-1]
+	expected, err := json.Marshal(entry.State)
+	if err != nil {
+		c.divergence = err
+		return
+	}
+	got, err := json.Marshal(c.sess.state())
+	if err != nil {
+		c.divergence = err
+		return
+	}
+	if string(expected) != string(got) {
+		c.divergence = fmt.Errorf("state after answer %d (%q) at prompt %q differs\n  expected: %s\n  got:      %s",
+			c.answered, entry.Answer, entry.Prompt, expected, got)
+	}
+}
+
+// finish
+// Checks the state after the last answer and that every answer was used, returning the first divergence.
+func (c *transcriptChecker) finish() error {
+	c.compareState()
+	if c.divergence != nil {
+		return c.divergence
+	}
+	if c.answered < len(c.entries) {
+		return fmt.Errorf("session ended after %d of %d answers", c.answered, len(c.entries))
+	}
+	return nil
+}
+
+// replayTranscript
+// Re-runs a recorded session without a terminal, feeding it the recorded answers and checking every state against
+// the transcript. Files named by ':load' and --scents-in are read again; ':save' and --scents-out are skipped.
+func replayTranscript(reader io.Reader, writer io.Writer) error {
+	header, entries, err := output.ReadTranscript(reader)
+	if err != nil {
+		return err
+	}
+	options, err := gridOptionsFromRecord(header.Options)
+	if err != nil {
+		return fmt.Errorf("invalid transcript options: %w", err)
+	}
+
+	var answers strings.Builder
+	for _, entry := range entries {
+		answers.WriteString(entry.Answer + "\n")
+	}
+
+	console := output.NewConsole(*bufio.NewReader(strings.NewReader(answers.String())), false)
+	console.SetWriter(io.Discard)
+	sess := &session{console: console, options: options, replaying: true, optimize: header.Options.Optimize}
+	checker := &transcriptChecker{sess: sess, entries: entries}
+	console.SetPromptObserver(checker)
+
+	// The error ending the session is not checked: a recorded session that ended in an error ends the same way
+	// when replayed, and any other ending leaves answers unused or a state that differs
+	_ = runRoverSimulation(sess, false)
+	if divergence := checker.finish(); divergence != nil {
+		return fmt.Errorf("replay diverged: %w", divergence)
+	}
+
+	fmt.Fprintf(writer, "Replayed %d answers: every state matches\n", len(entries))
+	return nil
+}
+
+func replayCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "replay",
+		Usage:     "Re-run a session recorded with --record and check that every answer leads to the recorded state",
+		ArgsUsage: "<transcript>",
+		Action: func(ctx context.Context, c *cli.Command) error {
+			if c.Args().Len() != 1 {
+				return fmt.Errorf("expected a transcript file")
+			}
+
+			file, err := os.Open(c.Args().First())
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			return replayTranscript(file, os.Stdout)
+		},
+	}
+}